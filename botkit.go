@@ -2,6 +2,7 @@ package snowman
 
 import (
 	"context"
+	"errors"
 	"math/rand"
 	"sync"
 	"time"
@@ -27,11 +28,85 @@ type UI interface {
 	Listen(ctx context.Context, receive func(msg Msg)) error
 }
 
+// ErrNotSupported is returned when an optional capability is not supported
+// by the UI.
+var ErrNotSupported = errors.New("not supported by the UI")
+
+// Typer can be implemented by UIs that can show a typing indicator to the
+// user identified by 'msg.To'.
+type Typer interface {
+	Typing(ctx context.Context, msg Msg) error
+}
+
+// Poster can be implemented by UIs that can return an ID for the messages
+// sent, which can be used later to update the message.
+type Poster interface {
+	Post(ctx context.Context, msg Msg) (id string, err error)
+}
+
+// Updater can be implemented by UIs that can replace the content of a message
+// sent earlier (identified by the ID returned by Poster) with msg.
+type Updater interface {
+	Update(ctx context.Context, id string, msg Msg) error
+}
+
+// Reactor can be implemented by UIs that can add an emoji reaction to a
+// message.
+type Reactor interface {
+	React(ctx context.Context, target Msg, emoji string) error
+}
+
 // Dialogue holds the conversational context of bot with a specific user.
+// State stored in the dialogue persists across messages from the same user.
 type Dialogue interface {
 	ID() string
 	Self() User
+	With() User
 	Say(ctx context.Context, body string) error
+
+	// Send sends a (possibly rich) message to the user. The To, From and At
+	// fields of the message are filled in by the dialogue.
+	Send(ctx context.Context, msg Msg) error
+
+	// Typing shows a typing indicator to the user. Returns ErrNotSupported
+	// if the UI does not implement Typer.
+	Typing(ctx context.Context) error
+
+	// Post sends the message like Send but returns an ID that can be used to
+	// Update the message later. If the UI does not implement Poster, message
+	// is sent using Say and empty ID is returned.
+	Post(ctx context.Context, msg Msg) (string, error)
+
+	// Update replaces the message identified by id with msg. Returns
+	// ErrNotSupported if the UI does not implement Updater.
+	Update(ctx context.Context, id string, msg Msg) error
+
+	// React adds an emoji reaction (e.g., 'thumbsup') to the target message.
+	// Returns ErrNotSupported if the UI does not implement Reactor.
+	React(ctx context.Context, target Msg, emoji string) error
+
+	// Get returns the value stored against the key in the dialogue state.
+	Get(key string) (interface{}, bool)
+
+	// Set stores the value against the key in the dialogue state.
+	Set(key string, val interface{})
+
+	// Delete removes the key from the dialogue state.
+	Delete(key string)
+
+	// Slot returns the value filled for the named slot. Slots are kept
+	// separate from the rest of the state so that they can be cleared
+	// together once the intent they belong to has been acted upon.
+	Slot(name string) (interface{}, bool)
+
+	// SetSlot sets the value for the named slot.
+	SetSlot(name string, val interface{})
+
+	// Slots returns a copy of all the slot values filled so far.
+	Slots() map[string]interface{}
+
+	// ClearSlots removes all the slot values.
+	ClearSlots()
 }
 
 // Bot represents an instance of the bot. A bot runs continuously blocking the
@@ -42,49 +117,336 @@ type Bot struct {
 	Self    User
 	Logger  Logger
 	Handler Handler
+	Store   DialogueStore
+	Jobs    JobStore
+
+	// DialogueTTL is the idle duration after which the state of a dialogue
+	// is reset. Zero value disables expiry.
+	DialogueTTL time.Duration
+
+	// DialogueKey decides which dialogue a message belongs to. Defaults to
+	// PerUser.
+	DialogueKey DialogueKeyFunc
+
+	// OnDialogueStart is invoked when a new dialogue begins. This includes
+	// the case when a user returns after the previous dialogue expired.
+	OnDialogueStart func(ctx context.Context, di Dialogue)
 
-	diLock    sync.RWMutex
-	dialogues map[string]*dialogueCtx
+	// OnDialogueEnd is invoked when a dialogue expires, after its state is
+	// removed. Dialogue holds the final state and can still be used to talk
+	// to the user. A dialogue never expires while one of its messages is
+	// being handled.
+	OnDialogueEnd func(ctx context.Context, di Dialogue)
+
+	// OnError is invoked when the Handler fails or panics while processing
+	// a message. Defaults to an ErrorReporter that only logs the errors.
+	OnError ErrorHandler
+
+	// ShutdownTimeout is the maximum time Run waits for the messages already
+	// received to be processed once the UI stops listening. Defaults to 10s.
+	ShutdownTimeout time.Duration
+
+	// Workers is the number of messages processed concurrently. Messages
+	// belonging to the same dialogue are always processed one at a time in
+	// the order they were received, so a slow dialogue only holds up its own
+	// messages. Defaults to 8.
+	Workers int
+
+	// QueueSize is the number of messages per worker that can be waiting to
+	// be processed. Receiving from the UI blocks when Workers*QueueSize
+	// messages are waiting. Defaults to 32.
+	QueueSize int
+
+	handler     Handler
+	workers     *workerPool
+	middlewares []Middleware
+	expiryLock  sync.Mutex
+	busy        map[string]struct{}
+	schedOnce   sync.Once
+	sched       *scheduler
 }
 
 // Run starts all the workers. Run blocks the current goroutine until the ctx
-// is cancelled or inputs is closed.
+// is cancelled or inputs is closed. Once the UI stops listening, the messages
+// already received are processed (waiting at most ShutdownTimeout) and the
+// stores are flushed before Run returns.
 func (bot *Bot) Run(ctx context.Context) error {
 	bot.init()
 
-	ctx, cancel := context.WithCancel(ctx)
+	// handlers run with a context that outlives ctx so that the messages
+	// in-flight can be completed during shutdown. workers must be running
+	// before anything (e.g., past-due jobs) can receive messages.
+	handlerCtx, cancelHandlers := context.WithCancel(context.Background())
+	defer cancelHandlers()
+	bot.workers = startWorkers(handlerCtx, bot.Workers, bot.QueueSize, bot.handle)
+
+	bgCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	return bot.UI.Listen(ctx, func(msg Msg) {
-		di := bot.allocDialogue(msg)
-		if msg.ctx == nil {
-			msg.ctx = ctx
-		}
+	var bg sync.WaitGroup
+	bg.Add(1)
+	go func() {
+		defer bg.Done()
+		bot.runJobs(bgCtx)
+	}()
 
-		if err := bot.Handler.Handle(&msg, di); err != nil {
-			bot.Logger.Errorf("handler error for message from '%s': %v", msg.From, err)
-		}
+	if bot.DialogueTTL > 0 {
+		bg.Add(1)
+		go func() {
+			defer bg.Done()
+			bot.expireDialogues(bgCtx)
+		}()
+	}
+
+	// messages are received with bgCtx so that a UI still delivering after
+	// Listen returns is never blocked on a full queue during shutdown.
+	err := bot.UI.Listen(ctx, func(msg Msg) {
+		bot.receive(bgCtx, msg)
 	})
+
+	cancel()
+	bg.Wait()
+	bot.shutdown(cancelHandlers)
+	return err
 }
 
-func (bot *Bot) allocDialogue(msg Msg) *dialogueCtx {
-	bot.diLock.Lock()
-	defer bot.diLock.Unlock()
+// shutdown waits for the workers to finish processing the messages already
+// queued and flushes the stores. If the workers do not finish within the
+// ShutdownTimeout, the handler context is cancelled and the remaining messages
+// are dropped.
+func (bot *Bot) shutdown(cancelHandlers context.CancelFunc) {
+	done := make(chan struct{})
+	go func() {
+		bot.workers.stop()
+		close(done)
+	}()
 
-	if _, found := bot.dialogues[msg.From.String()]; !found {
-		if bot.dialogues == nil {
-			bot.dialogues = map[string]*dialogueCtx{}
+	timer := time.NewTimer(bot.ShutdownTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+	case <-timer.C:
+		bot.Logger.Warnf("in-flight messages not completed within %s, abandoning them", bot.ShutdownTimeout)
+		cancelHandlers()
+	}
+
+	for _, store := range []interface{}{bot.Store, bot.Jobs} {
+		if f, ok := store.(Flusher); ok {
+			if err := f.Flush(); err != nil {
+				bot.Logger.Errorf("failed to flush store: %v", err)
+			}
 		}
-		bot.dialogues[msg.From.String()] = &dialogueCtx{
-			ui:   bot.UI,
-			self: bot.Self,
-			with: msg.From,
+	}
+}
+
+// receive queues a message received from the user (or generated by the
+// scheduler on behalf of the user) for processing. Messages received once the
+// bot has started shutting down are dropped.
+func (bot *Bot) receive(ctx context.Context, msg Msg) {
+	if !bot.workers.submit(ctx, bot.dialogueKey(msg), msg) {
+		bot.Logger.Warnf("dropped message from '%s' since the bot is shutting down", msg.From)
+		msg.finish()
+	}
+}
+
+// handle processes the message using the Handler and saves the dialogue
+// state.
+func (bot *Bot) handle(ctx context.Context, msg Msg) {
+	defer msg.finish()
+
+	di, err := bot.allocDialogue(ctx, msg)
+	if err != nil {
+		bot.Logger.Errorf("failed to load dialogue for message from '%s': %v", msg.From, err)
+		return
+	}
+
+	if msg.ctx == nil {
+		msg.ctx = ctx
+	}
+
+	if err := safeCall(func() error { return bot.handler.Handle(&msg, di) }); err != nil {
+		bot.handleError(&msg, di, err)
+	}
+
+	if err := bot.releaseDialogue(di); err != nil {
+		bot.Logger.Errorf("failed to save dialogue '%s': %v", di.ID(), err)
+	}
+}
+
+// allocDialogue loads the dialogue the message belongs to, starting a new one
+// if it does not exist or has expired, and marks it busy so that it does not
+// expire while the message is being handled. The hooks are run once the expiry
+// lock is released.
+func (bot *Bot) allocDialogue(ctx context.Context, msg Msg) (*dialogueCtx, error) {
+	id := bot.dialogueKey(msg)
+
+	var ended *DialogueState
+	bot.expiryLock.Lock()
+	state, err := bot.Store.Get(id)
+	if err == nil && bot.isExpired(*state) {
+		if err = bot.Store.Delete(id); err == nil {
+			ended, err = state, ErrNotFound
 		}
 	}
 
-	di := bot.dialogues[msg.From.String()]
-	di.with = msg.From
-	return di
+	isNew := errors.Is(err, ErrNotFound)
+	if isNew {
+		state, err = &DialogueState{ID: id}, nil
+	}
+
+	if err == nil {
+		state.With = msg.From
+		state.Origin = msg.Origin
+		state.Channel = msg.Channel
+		state.Thread = msg.Thread
+		state.LastActive = time.Now()
+		err = bot.Store.Put(*state)
+	}
+
+	if err == nil {
+		bot.busy[id] = struct{}{}
+	}
+	bot.expiryLock.Unlock()
+
+	if ended != nil {
+		bot.endDialogue(ctx, *ended)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	di := bot.newDialogue(*state)
+	if isNew && bot.OnDialogueStart != nil {
+		bot.runHook(di, func() { bot.OnDialogueStart(ctx, di) })
+	}
+	return di, nil
+}
+
+// releaseDialogue saves the state of the dialogue once the message has been
+// handled and allows it to expire again.
+func (bot *Bot) releaseDialogue(di *dialogueCtx) error {
+	state := di.snapshot()
+	state.LastActive = time.Now()
+
+	bot.expiryLock.Lock()
+	defer bot.expiryLock.Unlock()
+
+	delete(bot.busy, state.ID)
+	return bot.Store.Put(state)
+}
+
+// dialogueKey returns the ID of the dialogue the message belongs to. Origin
+// is included so that users of different UIs never share a dialogue.
+func (bot *Bot) dialogueKey(msg Msg) string {
+	key := bot.DialogueKey(msg)
+	if msg.Origin != "" {
+		return msg.Origin + "/" + key
+	}
+	return key
+}
+
+// minExpiryInterval is the minimum interval between the scans for expired
+// dialogues, regardless of how short the DialogueTTL is.
+const minExpiryInterval = 10 * time.Millisecond
+
+// expireDialogues periodically ends the dialogues that have been idle for
+// longer than DialogueTTL.
+func (bot *Bot) expireDialogues(ctx context.Context) {
+	interval := bot.DialogueTTL / 2
+	if interval < minExpiryInterval {
+		interval = minExpiryInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			var expired []string
+			if err := bot.Store.Range(func(state DialogueState) bool {
+				if bot.isExpired(state) {
+					expired = append(expired, state.ID)
+				}
+				return true
+			}); err != nil {
+				bot.Logger.Warnf("failed to scan dialogues for expiry: %v", err)
+				continue
+			}
+
+			for _, id := range expired {
+				bot.expireDialogue(ctx, id)
+			}
+		}
+	}
+}
+
+func (bot *Bot) expireDialogue(ctx context.Context, id string) {
+	bot.expiryLock.Lock()
+	state, err := bot.Store.Get(id)
+	_, busy := bot.busy[id]
+
+	// dialogue may have become active since the scan or may be being handled
+	// right now. so re-check.
+	expired := err == nil && !busy && bot.isExpired(*state)
+	if expired {
+		err = bot.Store.Delete(id)
+	}
+	bot.expiryLock.Unlock()
+
+	if !expired {
+		return
+	} else if err != nil {
+		bot.Logger.Errorf("failed to end dialogue '%s': %v", id, err)
+		return
+	}
+	bot.endDialogue(ctx, *state)
+}
+
+// endDialogue runs the OnDialogueEnd hook for a dialogue that has expired.
+func (bot *Bot) endDialogue(ctx context.Context, state DialogueState) {
+	if bot.OnDialogueEnd != nil {
+		di := bot.newDialogue(state)
+		bot.runHook(di, func() { bot.OnDialogueEnd(ctx, di) })
+	}
+}
+
+// handleError passes the failure to the OnError handler. A panic in the
+// OnError handler itself is logged instead of taking the worker down.
+func (bot *Bot) handleError(msg *Msg, di Dialogue, err error) {
+	herr := safeCall(func() error {
+		bot.OnError.HandleError(msg, di, err)
+		return nil
+	})
+	if herr != nil {
+		bot.Logger.Errorf("error handler failed for message from '%s' (error: %v): %v", msg.From, err, herr)
+	}
+}
+
+func (bot *Bot) runHook(di Dialogue, hook func()) {
+	err := safeCall(func() error {
+		hook()
+		return nil
+	})
+	if err != nil {
+		bot.Logger.Errorf("dialogue hook failed for '%s': %v", di.ID(), err)
+	}
+}
+
+func (bot *Bot) isExpired(state DialogueState) bool {
+	return bot.DialogueTTL > 0 && time.Since(state.LastActive) > bot.DialogueTTL
+}
+
+func (bot *Bot) newDialogue(state DialogueState) *dialogueCtx {
+	return &dialogueCtx{
+		ui:    bot.UI,
+		self:  bot.Self,
+		state: state,
+	}
 }
 
 func (bot *Bot) init() {
@@ -96,6 +458,32 @@ func (bot *Bot) init() {
 		bot.Logger = &StdLogger{}
 	}
 
+	if bot.Store == nil {
+		bot.Store = &MemoryStore{}
+	}
+
+	if bot.DialogueKey == nil {
+		bot.DialogueKey = PerUser
+	}
+
+	if bot.OnError == nil {
+		bot.OnError = &ErrorReporter{Logger: bot.Logger}
+	}
+
+	if bot.ShutdownTimeout <= 0 {
+		bot.ShutdownTimeout = defaultShutdownTimeout
+	}
+
+	if bot.Workers <= 0 {
+		bot.Workers = defaultWorkers
+	}
+
+	if bot.QueueSize <= 0 {
+		bot.QueueSize = defaultQueueSize
+	}
+
+	bot.busy = map[string]struct{}{}
+
 	if bot.Self.ID == "" {
 		bot.Self = User{
 			ID:      "snowy",
@@ -105,10 +493,18 @@ func (bot *Bot) init() {
 	}
 
 	if bot.Handler == nil {
-		bot.Handler = Fn(func(msg *Msg, di Dialogue) error {
-			return di.Say(msg.Context(), dumbResponse())
-		})
+		bot.Handler = NotUnderstood()
 	}
+	bot.handler = Chain(bot.Handler, bot.middlewares...)
+}
+
+// NotUnderstood returns a Handler that always responds with a randomly chosen
+// "I don't understand" message. This is used as the default Handler when none
+// is configured.
+func NotUnderstood() Handler {
+	return Fn(func(msg *Msg, di Dialogue) error {
+		return di.Say(msg.Context(), dumbResponse())
+	})
 }
 
 func dumbResponse() string {
@@ -126,21 +522,3 @@ type Fn func(msg *Msg, di Dialogue) error
 
 // Handle simply dispatches the args to the wrapped function.
 func (fn Fn) Handle(msg *Msg, di Dialogue) error { return fn(msg, di) }
-
-// dialogueCtx represents the context of a dialogue.
-type dialogueCtx struct {
-	ui   UI
-	self User
-	with User
-}
-
-func (di *dialogueCtx) ID() string { return di.with.String() }
-func (di *dialogueCtx) Self() User { return di.self }
-func (di *dialogueCtx) Say(ctx context.Context, body string) error {
-	return di.ui.Say(ctx, Msg{
-		At:   time.Now(),
-		To:   di.with,
-		From: di.self,
-		Body: body,
-	})
-}
@@ -0,0 +1,345 @@
+package snowman_test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spy16/snowman"
+)
+
+func TestBot_Run(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Ordering", func(t *testing.T) {
+		ui := newTestUI()
+
+		var mu sync.Mutex
+		got := map[string][]string{}
+		done := make(chan struct{}, 200)
+		startBot(t, &snowman.Bot{
+			UI:      ui,
+			Workers: 4,
+			Handler: snowman.Fn(func(msg *snowman.Msg, _ snowman.Dialogue) error {
+				mu.Lock()
+				got[msg.From.ID] = append(got[msg.From.ID], msg.Body)
+				mu.Unlock()
+				done <- struct{}{}
+				return nil
+			}),
+		})
+
+		for i := 0; i < 100; i++ {
+			ui.in <- userMsg("a", strconv.Itoa(i))
+			ui.in <- userMsg("b", strconv.Itoa(i))
+		}
+		waitN(t, done, 200)
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, user := range []string{"a", "b"} {
+			for i, body := range got[user] {
+				if body != strconv.Itoa(i) {
+					t.Fatalf("messages of '%s' processed out of order: %v", user, got[user])
+				}
+			}
+		}
+	})
+
+	t.Run("Concurrency", func(t *testing.T) {
+		ui := newTestUI()
+
+		blocked := make(chan struct{}, 1)
+		done := make(chan struct{}, 20)
+		release := make(chan struct{})
+		defer close(release)
+		startBot(t, &snowman.Bot{
+			UI:      ui,
+			Workers: 2,
+			Handler: snowman.Fn(func(msg *snowman.Msg, _ snowman.Dialogue) error {
+				if msg.From.ID == "slow" {
+					blocked <- struct{}{}
+					<-release
+					return nil
+				}
+				done <- struct{}{}
+				return nil
+			}),
+		})
+
+		// while the slow dialogue blocks, all the other dialogues must still
+		// be processed, not only the ones that do not share its worker.
+		ui.in <- userMsg("slow", "hi")
+		waitN(t, blocked, 1)
+		for i := 0; i < 20; i++ {
+			ui.in <- userMsg("user-"+strconv.Itoa(i), "hi")
+		}
+		waitN(t, done, 20)
+	})
+}
+
+func TestBot_Expiry(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Hooks", func(t *testing.T) {
+		ui := newTestUI()
+
+		started := make(chan struct{}, 2)
+		ended := make(chan interface{}, 2)
+		handled := make(chan struct{}, 2)
+		startBot(t, &snowman.Bot{
+			UI:          ui,
+			DialogueTTL: 50 * time.Millisecond,
+			OnDialogueStart: func(_ context.Context, _ snowman.Dialogue) {
+				started <- struct{}{}
+			},
+			OnDialogueEnd: func(_ context.Context, di snowman.Dialogue) {
+				val, _ := di.Get("last")
+				ended <- val
+			},
+			Handler: snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+				di.Set("last", msg.Body)
+				handled <- struct{}{}
+				return nil
+			}),
+		})
+
+		ui.in <- userMsg("a", "first")
+		waitN(t, started, 1)
+		waitN(t, handled, 1)
+
+		select {
+		case val := <-ended:
+			if val != "first" {
+				t.Errorf("OnDialogueEnd want final state 'first', got %v", val)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("OnDialogueEnd not invoked for the idle dialogue")
+		}
+
+		ui.in <- userMsg("a", "second")
+		waitN(t, started, 1)
+		waitN(t, handled, 1)
+	})
+
+	t.Run("NotWhileHandling", func(t *testing.T) {
+		ui := newTestUI()
+		store := &snowman.MemoryStore{}
+
+		var mu sync.Mutex
+		ends := 0
+		handled := make(chan struct{}, 1)
+		startBot(t, &snowman.Bot{
+			UI:          ui,
+			Store:       store,
+			DialogueTTL: 20 * time.Millisecond,
+			OnDialogueEnd: func(context.Context, snowman.Dialogue) {
+				mu.Lock()
+				ends++
+				mu.Unlock()
+			},
+			Handler: snowman.Fn(func(msg *snowman.Msg, _ snowman.Dialogue) error {
+				time.Sleep(100 * time.Millisecond)
+				handled <- struct{}{}
+				return nil
+			}),
+		})
+
+		ui.in <- userMsg("a", "slow")
+		waitN(t, handled, 1)
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		if ends != 0 {
+			t.Errorf("OnDialogueEnd want not invoked while handling, got %d calls", ends)
+		}
+		mu.Unlock()
+
+		time.Sleep(100 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		if ends != 1 {
+			t.Errorf("OnDialogueEnd want 1 call once idle, got %d", ends)
+		}
+		if store.Len() != 0 {
+			t.Errorf("want expired dialogue removed from store, got %d dialogues", store.Len())
+		}
+	})
+
+	t.Run("TinyTTL", func(t *testing.T) {
+		ui := newTestUI()
+
+		ended := make(chan struct{}, 1)
+		startBot(t, &snowman.Bot{
+			UI:          ui,
+			DialogueTTL: time.Nanosecond,
+			OnDialogueEnd: func(context.Context, snowman.Dialogue) {
+				ended <- struct{}{}
+			},
+			Handler: snowman.Fn(func(*snowman.Msg, snowman.Dialogue) error { return nil }),
+		})
+
+		ui.in <- userMsg("a", "hi")
+		waitN(t, ended, 1)
+	})
+}
+
+func TestBot_Shutdown(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Drain", func(t *testing.T) {
+		ui := newTestUI()
+
+		var mu sync.Mutex
+		handled := 0
+		stop := startBot(t, &snowman.Bot{
+			UI: ui,
+			Handler: snowman.Fn(func(msg *snowman.Msg, _ snowman.Dialogue) error {
+				time.Sleep(20 * time.Millisecond)
+				mu.Lock()
+				handled++
+				mu.Unlock()
+				return nil
+			}),
+		})
+
+		for i := 0; i < 5; i++ {
+			ui.in <- userMsg("a", strconv.Itoa(i))
+		}
+		if err := stop(); err != nil {
+			t.Fatalf("Run() unexpected error: %v", err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if handled != 5 {
+			t.Errorf("want all 5 in-flight messages handled before Run returns, got %d", handled)
+		}
+	})
+
+	t.Run("ReceiveAfterStop", func(t *testing.T) {
+		ui := &lingeringUI{stop: make(chan struct{})}
+		stop := startBot(t, &snowman.Bot{UI: ui, Handler: snowman.Fn(func(*snowman.Msg, snowman.Dialogue) error {
+			return nil
+		})})
+
+		time.Sleep(20 * time.Millisecond)
+		if err := stop(); err != nil {
+			t.Fatalf("Run() unexpected error: %v", err)
+		}
+
+		// the UI keeps delivering after Listen returned. these must be
+		// dropped instead of panicking on a closed queue.
+		time.Sleep(20 * time.Millisecond)
+		close(ui.stop)
+		ui.wg.Wait()
+	})
+}
+
+// lingeringUI keeps delivering messages from a background goroutine until
+// stop is closed, even after Listen returns.
+type lingeringUI struct {
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func (ui *lingeringUI) Say(context.Context, snowman.Msg) error { return nil }
+
+func (ui *lingeringUI) Listen(ctx context.Context, receive func(msg snowman.Msg)) error {
+	ui.wg.Add(1)
+	go func() {
+		defer ui.wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-ui.stop:
+				return
+			default:
+				receive(userMsg("user-"+strconv.Itoa(i%10), "hi"))
+			}
+		}
+	}()
+
+	<-ctx.Done()
+	return nil
+}
+
+// testUI delivers the messages sent on in to the bot and records the messages
+// said by the bot on out.
+type testUI struct {
+	in  chan snowman.Msg
+	out chan snowman.Msg
+}
+
+func newTestUI() *testUI {
+	return &testUI{
+		in:  make(chan snowman.Msg),
+		out: make(chan snowman.Msg, 100),
+	}
+}
+
+func (ui *testUI) Say(_ context.Context, msg snowman.Msg) error {
+	ui.out <- msg
+	return nil
+}
+
+func (ui *testUI) Listen(ctx context.Context, receive func(msg snowman.Msg)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg := <-ui.in:
+			receive(msg)
+		}
+	}
+}
+
+// startBot runs the bot until the returned function is called or the test
+// ends. The returned function stops the bot and returns the error from Run.
+func startBot(t *testing.T, bot *snowman.Bot) func() error {
+	t.Helper()
+
+	if bot.Logger == nil {
+		bot.Logger = snowman.NoOpLogger{}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- bot.Run(ctx) }()
+
+	var once sync.Once
+	var err error
+	stop := func() error {
+		once.Do(func() {
+			cancel()
+			err = <-errCh
+		})
+		return err
+	}
+	t.Cleanup(func() { _ = stop() })
+	return stop
+}
+
+func userMsg(from, body string) snowman.Msg {
+	return snowman.Msg{
+		At:   time.Now(),
+		From: snowman.User{ID: from, Name: from},
+		Body: body,
+	}
+}
+
+// waitN waits for n signals on ch, failing the test if they do not arrive in
+// time.
+func waitN(t *testing.T, ch <-chan struct{}, n int) {
+	t.Helper()
+
+	timeout := time.After(2 * time.Second)
+	for i := 0; i < n; i++ {
+		select {
+		case <-ch:
+		case <-timeout:
+			t.Fatalf("got %d of %d signals before timeout", i, n)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"log"
 	"os/signal"
+	"strings"
 	"syscall"
 	"text/template"
 
@@ -14,14 +15,34 @@ import (
 
 	"github.com/spy16/snowman"
 	"github.com/spy16/snowman/regex"
+	"github.com/spy16/snowman/router"
 )
 
 var (
 	name       = flag.String("name", "Snowy", "Name for the bot")
 	slackToken = flag.String("slack", "", "Slack Bot Token")
+	slackApp   = flag.String("slack-app-token", "", "Slack App-Level Token (enables Socket Mode)")
+	slackSign  = flag.String("slack-signing-secret", "", "Slack Signing Secret (enables Events API)")
+	slackAddr  = flag.String("slack-events", ":3000", "Address to accept Slack Events API requests on")
+	ircAddr    = flag.String("irc", "", "IRC server to connect to (e.g., 'irc.libera.chat:6667')")
+	ircChans   = flag.String("irc-channels", "", "Comma separated IRC channels to join")
+	tgToken    = flag.String("telegram", "", "Telegram Bot Token")
+	httpAddr   = flag.String("http", "", "Address to accept messages over HTTP on (e.g., ':8080')")
+	webAddr    = flag.String("web", "", "Address to serve the web chat UI on (e.g., ':8081')")
+	socketAddr = flag.String("socket", "", "Address to accept line based connections on (e.g., ':9000' or 'unix:/tmp/snowy.sock')")
 	intentsDir = flag.String("intents", "./samples", "Intent files directory")
+	stateFile  = flag.String("dialogues", "", "File to persist dialogue state in (in-memory if empty)")
+	jobsFile   = flag.String("jobs", "", "File to persist scheduled jobs in (in-memory if empty)")
+	scope      = flag.String("scope", "user", "Dialogue scope (user, channel, thread or user-in-channel)")
 )
 
+var scopes = map[string]snowman.DialogueKeyFunc{
+	"user":            snowman.PerUser,
+	"channel":         snowman.PerChannel,
+	"thread":          snowman.PerThread,
+	"user-in-channel": snowman.PerUserInChannel,
+}
+
 func main() {
 	flag.Parse()
 	logger := logrus.New()
@@ -29,40 +50,122 @@ func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
-	handler := regex.New(simpleHandler())
-	if err := regex.Load(handler, *intentsDir); err != nil {
+	classifier := regex.New(nil)
+	if err := regex.Load(classifier, *intentsDir); err != nil {
 		log.Fatalf("failed to load intents from '%s': %v", *intentsDir, err)
 	}
 
-	var ui snowman.UI = &snowman.ConsoleUI{Prompt: "user=> "}
+	uis := map[string]snowman.UI{}
 	if *slackToken != "" {
-		ui = &snowman.SlackUI{
+		slackUI := &snowman.SlackUI{
 			Token:         *slackToken,
 			Logger:        logger,
 			EnableChannel: true,
 			ThreadDirect:  false,
+			AppToken:      *slackApp,
+			SigningSecret: *slackSign,
+			Addr:          *slackAddr,
+		}
+
+		if *slackApp != "" {
+			slackUI.Mode = snowman.SlackSocketMode
+		} else if *slackSign != "" {
+			slackUI.Mode = snowman.SlackEvents
+		}
+		uis["slack"] = slackUI
+	}
+
+	if *ircAddr != "" {
+		var channels []string
+		if *ircChans != "" {
+			channels = strings.Split(*ircChans, ",")
+		}
+		uis["irc"] = &snowman.IRCUI{Addr: *ircAddr, Nick: *name, Channels: channels, Logger: logger}
+	}
+
+	if *tgToken != "" {
+		uis["telegram"] = &snowman.TelegramUI{Token: *tgToken, Logger: logger}
+	}
+
+	if *httpAddr != "" {
+		uis["http"] = &snowman.HTTPUI{Addr: *httpAddr, Logger: logger}
+	}
+
+	if *webAddr != "" {
+		uis["web"] = &snowman.WebSocketUI{Addr: *webAddr, Title: *name, Logger: logger}
+	}
+
+	if *socketAddr != "" {
+		network, addr := "tcp", *socketAddr
+		if strings.HasPrefix(addr, "unix:") {
+			network, addr = "unix", strings.TrimPrefix(addr, "unix:")
 		}
+		uis["socket"] = &snowman.SocketUI{Network: network, Addr: addr, Logger: logger}
+	}
+
+	var ui snowman.UI = &snowman.ConsoleUI{Prompt: "user=> "}
+	if len(uis) == 1 {
+		for _, only := range uis {
+			ui = only
+		}
+	} else if len(uis) > 1 {
+		ui = &snowman.MultiUI{UIs: uis}
+	}
+
+	var store snowman.DialogueStore = &snowman.MemoryStore{MaxSize: 10000}
+	if *stateFile != "" {
+		fs, err := snowman.OpenFileStore(*stateFile)
+		if err != nil {
+			log.Fatalf("failed to open dialogue store '%s': %v", *stateFile, err)
+		}
+		defer fs.Close()
+		fs.Logger = logger
+		store = fs
+	}
+
+	dialogueKey, found := scopes[*scope]
+	if !found {
+		log.Fatalf("unknown dialogue scope '%s'", *scope)
+	}
+
+	var jobs snowman.JobStore = &snowman.MemoryJobStore{}
+	if *jobsFile != "" {
+		jobs = &snowman.FileJobStore{Path: *jobsFile}
 	}
 
 	snowy := snowman.Bot{
 		UI:      ui,
 		Logger:  logger,
-		Handler: handler,
+		Handler: newRouter(),
+		Store:   store,
+		Jobs:    jobs,
+		OnError: &snowman.ErrorReporter{
+			Logger:  logger,
+			Apology: "Sorry, something went wrong while processing that 😔",
+		},
 		Self: snowman.User{
 			ID:   *name,
 			Name: *name,
 		},
+		DialogueKey: dialogueKey,
 	}
+	snowy.Use(snowman.LogMessages(logger), snowman.ShowTyping(), classifier.Middleware)
+
 	if err := snowy.Run(ctx); err != nil {
 		log.Fatalf("snowy exited: %v", err)
 	}
 }
 
-func simpleHandler() snowman.Fn {
+func newRouter() *router.Router {
+	rt := router.New(snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+		return di.Say(msg.Context(), "I could not understand what you just said 😐")
+	}))
+	rt.Glob("*", templateResponder())
+	return rt
+}
+
+func templateResponder() snowman.Fn {
 	return func(msg *snowman.Msg, di snowman.Dialogue) error {
-		if len(msg.Intents) == 0 {
-			return di.Say(msg.Context(), "I could not understand what you just said 😐")
-		}
 		intent := msg.Intents[0]
 
 		data := map[string]interface{}{}
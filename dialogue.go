@@ -0,0 +1,177 @@
+package snowman
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+var _ Dialogue = (*dialogueCtx)(nil)
+
+// DialogueKeyFunc returns the key identifying the dialogue a message belongs
+// to. Messages with the same key share the dialogue state.
+type DialogueKeyFunc func(msg Msg) string
+
+// PerUser keys dialogues by the user, so a user shares one dialogue across all
+// the channels and threads. This is the default.
+func PerUser(msg Msg) string { return msg.From.String() }
+
+// PerChannel keys dialogues by the channel, so all the users in a channel
+// share one dialogue. Falls back to PerUser if the UI provides no channel.
+func PerChannel(msg Msg) string {
+	if msg.Channel == "" {
+		return PerUser(msg)
+	}
+	return "channel:" + msg.Channel
+}
+
+// PerThread keys dialogues by the thread, so every thread is an independent
+// dialogue shared by its participants. Falls back to PerChannel for messages
+// that are not part of a thread.
+func PerThread(msg Msg) string {
+	if msg.Thread == "" {
+		return PerChannel(msg)
+	}
+	return "thread:" + msg.Channel + "/" + msg.Thread
+}
+
+// PerUserInChannel keys dialogues by the user and the channel, so a user has
+// independent dialogues in different channels. Falls back to PerUser if the
+// UI provides no channel.
+func PerUserInChannel(msg Msg) string {
+	if msg.Channel == "" {
+		return PerUser(msg)
+	}
+	return "channel:" + msg.Channel + "/" + msg.From.String()
+}
+
+// dialogueCtx represents the context of a dialogue.
+type dialogueCtx struct {
+	ui   UI
+	self User
+
+	mu    sync.RWMutex
+	state DialogueState
+}
+
+func (di *dialogueCtx) ID() string { return di.state.ID }
+func (di *dialogueCtx) Self() User { return di.self }
+func (di *dialogueCtx) With() User {
+	di.mu.RLock()
+	defer di.mu.RUnlock()
+	return di.state.With
+}
+func (di *dialogueCtx) Say(ctx context.Context, body string) error {
+	return di.Send(ctx, Msg{Body: body})
+}
+
+func (di *dialogueCtx) Send(ctx context.Context, msg Msg) error {
+	return di.ui.Say(ctx, di.outgoing(msg))
+}
+
+func (di *dialogueCtx) Typing(ctx context.Context) error {
+	typer, ok := di.ui.(Typer)
+	if !ok {
+		return ErrNotSupported
+	}
+	return typer.Typing(ctx, di.outgoing(Msg{}))
+}
+
+func (di *dialogueCtx) Post(ctx context.Context, msg Msg) (string, error) {
+	poster, ok := di.ui.(Poster)
+	if !ok {
+		return "", di.Send(ctx, msg)
+	}
+	return poster.Post(ctx, di.outgoing(msg))
+}
+
+func (di *dialogueCtx) Update(ctx context.Context, id string, msg Msg) error {
+	updater, ok := di.ui.(Updater)
+	if !ok {
+		return ErrNotSupported
+	}
+	return updater.Update(ctx, id, di.outgoing(msg))
+}
+
+func (di *dialogueCtx) React(ctx context.Context, target Msg, emoji string) error {
+	reactor, ok := di.ui.(Reactor)
+	if !ok {
+		return ErrNotSupported
+	}
+	if target.Origin == "" {
+		target.Origin = di.origin()
+	}
+	return reactor.React(ctx, target, emoji)
+}
+
+// outgoing fills the addressing fields of a message sent in this dialogue.
+func (di *dialogueCtx) outgoing(msg Msg) Msg {
+	msg.At = time.Now()
+	msg.To = di.With()
+	msg.From = di.self
+	msg.Origin = di.origin()
+	return msg
+}
+
+func (di *dialogueCtx) Get(key string) (interface{}, bool) {
+	di.mu.RLock()
+	defer di.mu.RUnlock()
+	val, found := di.state.Values[key]
+	return val, found
+}
+
+func (di *dialogueCtx) Set(key string, val interface{}) {
+	di.mu.Lock()
+	defer di.mu.Unlock()
+	if di.state.Values == nil {
+		di.state.Values = map[string]interface{}{}
+	}
+	di.state.Values[key] = val
+}
+
+func (di *dialogueCtx) Delete(key string) {
+	di.mu.Lock()
+	defer di.mu.Unlock()
+	delete(di.state.Values, key)
+}
+
+func (di *dialogueCtx) Slot(name string) (interface{}, bool) {
+	di.mu.RLock()
+	defer di.mu.RUnlock()
+	val, found := di.state.Slots[name]
+	return val, found
+}
+
+func (di *dialogueCtx) SetSlot(name string, val interface{}) {
+	di.mu.Lock()
+	defer di.mu.Unlock()
+	if di.state.Slots == nil {
+		di.state.Slots = map[string]interface{}{}
+	}
+	di.state.Slots[name] = val
+}
+
+func (di *dialogueCtx) Slots() map[string]interface{} {
+	di.mu.RLock()
+	defer di.mu.RUnlock()
+	return cloneMerge(di.state.Slots)
+}
+
+func (di *dialogueCtx) ClearSlots() {
+	di.mu.Lock()
+	defer di.mu.Unlock()
+	di.state.Slots = nil
+}
+
+func (di *dialogueCtx) origin() string {
+	di.mu.RLock()
+	defer di.mu.RUnlock()
+	return di.state.Origin
+}
+
+// snapshot returns a copy of the current dialogue state.
+func (di *dialogueCtx) snapshot() DialogueState {
+	di.mu.RLock()
+	defer di.mu.RUnlock()
+	return di.state.Clone()
+}
@@ -0,0 +1,266 @@
+package snowman_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spy16/snowman"
+)
+
+func TestDialogue_State(t *testing.T) {
+	t.Parallel()
+
+	handler := snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+		switch msg.Body {
+		case "set":
+			n, _ := snowman.GetInt(di, "count")
+			di.Set("count", n+1)
+			di.Set("name", msg.From.ID)
+			di.SetSlot("city", "Paris")
+
+		case "clear":
+			di.Delete("name")
+			di.ClearSlots()
+		}
+
+		n, _ := snowman.GetInt(di, "count")
+		name, _ := snowman.GetString(di, "name")
+		city, _ := di.Slot("city")
+		return di.Say(msg.Context(), fmt.Sprintf("count=%d name=%s city=%v", n, name, city))
+	})
+
+	// every run is a restart of the bot on the same store, so the state
+	// must also survive being persisted and restored.
+	path := filepath.Join(t.TempDir(), "dialogues.jsonl")
+	run := func(steps ...[3]string) {
+		t.Helper()
+
+		store, err := snowman.OpenFileStore(path)
+		if err != nil {
+			t.Fatalf("OpenFileStore() unexpected error: %v", err)
+		}
+		defer store.Close()
+
+		ui := newTestUI()
+		stop := startBot(t, &snowman.Bot{UI: ui, Store: store, Handler: handler})
+		defer stop()
+
+		for _, step := range steps {
+			from, body, want := step[0], step[1], step[2]
+			ui.in <- userMsg(from, body)
+			expectSaid(t, ui, from, want)
+		}
+	}
+
+	run(
+		[3]string{"alice", "set", "count=1 name=alice city=Paris"},
+		[3]string{"alice", "set", "count=2 name=alice city=Paris"},
+		[3]string{"bob", "get", "count=0 name= city=<nil>"},
+	)
+	run(
+		[3]string{"alice", "get", "count=2 name=alice city=Paris"},
+		[3]string{"alice", "clear", "count=2 name= city=<nil>"},
+	)
+	run(
+		[3]string{"alice", "get", "count=2 name= city=<nil>"},
+	)
+}
+
+func TestDialogue_Capabilities(t *testing.T) {
+	t.Parallel()
+
+	handler := snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+		ctx := msg.Context()
+
+		var results []string
+		record := func(name string, err error) {
+			results = append(results, fmt.Sprintf("%s: %v", name, err))
+		}
+
+		record("typing", di.Typing(ctx))
+		id, err := di.Post(ctx, snowman.Msg{Body: "posted"})
+		record("post '"+id+"'", err)
+		record("update", di.Update(ctx, id, snowman.Msg{Body: "updated"}))
+		// the target has no Origin, like a message kept from earlier.
+		record("react", di.React(ctx, snowman.Msg{ID: msg.ID}, "thumbsup"))
+		return di.Say(ctx, strings.Join(results, ", "))
+	})
+
+	msg := userMsg("alice", "hi")
+	msg.ID = "m1"
+
+	t.Run("PlainUI", func(t *testing.T) {
+		ui := newTestUI()
+		startBot(t, &snowman.Bot{UI: ui, Handler: handler})
+
+		ui.in <- msg
+		// Post falls back to Say and returns an empty ID.
+		expectSaid(t, ui, "alice", "posted")
+		expectSaid(t, ui, "alice", "typing: not supported by the UI, post '': <nil>, "+
+			"update: not supported by the UI, react: not supported by the UI")
+	})
+
+	t.Run("MultiUI", func(t *testing.T) {
+		plain := newTestUI()
+		rich := &richUI{testUI: newTestUI(), calls: make(chan string, 10)}
+		startBot(t, &snowman.Bot{
+			UI:      &snowman.MultiUI{UIs: map[string]snowman.UI{"plain": plain, "rich": rich}},
+			Handler: handler,
+		})
+
+		rich.in <- msg
+		expectSaid(t, rich.testUI, "alice", "typing: <nil>, post 'id-1': <nil>, update: <nil>, react: <nil>")
+		for _, want := range []string{"typing", "post posted", "update id-1 updated", "react m1 thumbsup"} {
+			if got := <-rich.calls; got != want {
+				t.Errorf("want '%s' forwarded to the rich ui, got '%s'", want, got)
+			}
+		}
+
+		plain.in <- msg
+		expectSaid(t, plain, "alice", "posted")
+		expectSaid(t, plain, "alice", "typing: not supported by the UI, post '': <nil>, "+
+			"update: not supported by the UI, react: not supported by the UI")
+	})
+}
+
+// richUI is a testUI that supports all the optional capabilities and records
+// their use on calls.
+type richUI struct {
+	*testUI
+	calls chan string
+}
+
+func (ui *richUI) Typing(context.Context, snowman.Msg) error {
+	ui.calls <- "typing"
+	return nil
+}
+
+func (ui *richUI) Post(_ context.Context, msg snowman.Msg) (string, error) {
+	ui.calls <- "post " + msg.Body
+	return "id-1", nil
+}
+
+func (ui *richUI) Update(_ context.Context, id string, msg snowman.Msg) error {
+	ui.calls <- "update " + id + " " + msg.Body
+	return nil
+}
+
+func (ui *richUI) React(_ context.Context, target snowman.Msg, emoji string) error {
+	ui.calls <- "react " + target.ID + " " + emoji
+	return nil
+}
+
+func TestDialogueKeyFunc(t *testing.T) {
+	t.Parallel()
+
+	alice := snowman.User{ID: "alice"}
+	bob := snowman.User{ID: "bob"}
+
+	table := []struct {
+		title string
+		fn    snowman.DialogueKeyFunc
+		a, b  snowman.Msg
+		same  bool
+	}{
+		{
+			title: "PerUser/AcrossChannels",
+			fn:    snowman.PerUser,
+			a:     snowman.Msg{From: alice, Channel: "c1"},
+			b:     snowman.Msg{From: alice, Channel: "c2"},
+			same:  true,
+		},
+		{
+			title: "PerUser/DifferentUsers",
+			fn:    snowman.PerUser,
+			a:     snowman.Msg{From: alice, Channel: "c1"},
+			b:     snowman.Msg{From: bob, Channel: "c1"},
+		},
+		{
+			title: "PerChannel/DifferentUsers",
+			fn:    snowman.PerChannel,
+			a:     snowman.Msg{From: alice, Channel: "c1"},
+			b:     snowman.Msg{From: bob, Channel: "c1"},
+			same:  true,
+		},
+		{
+			title: "PerChannel/DifferentChannels",
+			fn:    snowman.PerChannel,
+			a:     snowman.Msg{From: alice, Channel: "c1"},
+			b:     snowman.Msg{From: alice, Channel: "c2"},
+		},
+		{
+			title: "PerChannel/NoChannel",
+			fn:    snowman.PerChannel,
+			a:     snowman.Msg{From: alice},
+			b:     snowman.Msg{From: bob},
+		},
+		{
+			title: "PerThread/SameThread",
+			fn:    snowman.PerThread,
+			a:     snowman.Msg{ID: "1", From: alice, Channel: "c1", Thread: "t1"},
+			b:     snowman.Msg{ID: "2", From: bob, Channel: "c1", Thread: "t1"},
+			same:  true,
+		},
+		{
+			title: "PerThread/DifferentThreads",
+			fn:    snowman.PerThread,
+			a:     snowman.Msg{From: alice, Channel: "c1", Thread: "t1"},
+			b:     snowman.Msg{From: alice, Channel: "c1", Thread: "t2"},
+		},
+		{
+			title: "PerThread/UnthreadedFallsBackToChannel",
+			fn:    snowman.PerThread,
+			a:     snowman.Msg{ID: "1", From: alice, Channel: "c1"},
+			b:     snowman.Msg{ID: "2", From: bob, Channel: "c1"},
+			same:  true,
+		},
+		{
+			title: "PerThread/ThreadedVsUnthreaded",
+			fn:    snowman.PerThread,
+			a:     snowman.Msg{From: alice, Channel: "c1", Thread: "t1"},
+			b:     snowman.Msg{From: alice, Channel: "c1"},
+		},
+		{
+			title: "PerUserInChannel/SameUserAndChannel",
+			fn:    snowman.PerUserInChannel,
+			a:     snowman.Msg{ID: "1", From: alice, Channel: "c1"},
+			b:     snowman.Msg{ID: "2", From: alice, Channel: "c1", Thread: "t1"},
+			same:  true,
+		},
+		{
+			title: "PerUserInChannel/DifferentChannels",
+			fn:    snowman.PerUserInChannel,
+			a:     snowman.Msg{From: alice, Channel: "c1"},
+			b:     snowman.Msg{From: alice, Channel: "c2"},
+		},
+		{
+			title: "PerUserInChannel/DifferentUsers",
+			fn:    snowman.PerUserInChannel,
+			a:     snowman.Msg{From: alice, Channel: "c1"},
+			b:     snowman.Msg{From: bob, Channel: "c1"},
+		},
+		{
+			title: "PerUserInChannel/NoChannel",
+			fn:    snowman.PerUserInChannel,
+			a:     snowman.Msg{From: alice},
+			b:     snowman.Msg{From: alice},
+			same:  true,
+		},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.title, func(t *testing.T) {
+			a, b := tt.fn(tt.a), tt.fn(tt.b)
+			if a == "" || b == "" {
+				t.Fatalf("key want non-empty, got '%s' and '%s'", a, b)
+			}
+
+			if (a == b) != tt.same {
+				t.Errorf("keys '%s' and '%s': want same=%t", a, b, tt.same)
+			}
+		})
+	}
+}
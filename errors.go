@@ -0,0 +1,84 @@
+package snowman
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+var _ ErrorHandler = (*ErrorReporter)(nil)
+
+// ErrorHandler is invoked by the bot when the Handler returns an error or
+// panics while processing a message.
+type ErrorHandler interface {
+	HandleError(msg *Msg, di Dialogue, err error)
+}
+
+// ErrorFn is an adaptor to implement ErrorHandler using simple Go func values.
+type ErrorFn func(msg *Msg, di Dialogue, err error)
+
+// HandleError simply dispatches the args to the wrapped function.
+func (fn ErrorFn) HandleError(msg *Msg, di Dialogue, err error) { fn(msg, di, err) }
+
+// PanicError is passed to the ErrorHandler when the Handler panics.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (pe *PanicError) Error() string { return fmt.Sprintf("panic: %v", pe.Value) }
+
+// ErrorReporter implements an ErrorHandler that logs the errors (along with
+// the stack trace in case of panics), counts them and optionally replies to
+// the user with an apology.
+type ErrorReporter struct {
+	Logger  Logger
+	Apology string
+
+	failures int64
+	panics   int64
+}
+
+// HandleError logs the error, updates the counters and says the apology to
+// the user if one is configured.
+func (er *ErrorReporter) HandleError(msg *Msg, di Dialogue, err error) {
+	atomic.AddInt64(&er.failures, 1)
+
+	var pe *PanicError
+	if errors.As(err, &pe) {
+		atomic.AddInt64(&er.panics, 1)
+		er.logger().Errorf("handler panicked for message from '%s': %v\n%s", msg.From, pe.Value, pe.Stack)
+	} else {
+		er.logger().Errorf("handler error for message from '%s': %v", msg.From, err)
+	}
+
+	if er.Apology != "" {
+		if sayErr := di.Say(msg.Context(), er.Apology); sayErr != nil {
+			er.logger().Warnf("failed to apologise to '%s': %v", msg.From, sayErr)
+		}
+	}
+}
+
+// Failures returns the number of errors handled so far (panics included).
+func (er *ErrorReporter) Failures() int64 { return atomic.LoadInt64(&er.failures) }
+
+// Panics returns the number of panics handled so far.
+func (er *ErrorReporter) Panics() int64 { return atomic.LoadInt64(&er.panics) }
+
+func (er *ErrorReporter) logger() Logger {
+	if er.Logger == nil {
+		return NoOpLogger{}
+	}
+	return er.Logger
+}
+
+// safeCall invokes fn and turns a panic into a PanicError.
+func safeCall(fn func() error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	return fn()
+}
@@ -0,0 +1,101 @@
+package snowman_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spy16/snowman"
+)
+
+func TestBot_Errors(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Recover", func(t *testing.T) {
+		ui := newTestUI()
+		errs := make(chan error, 1)
+		startBot(t, &snowman.Bot{
+			UI:      ui,
+			Handler: failingHandler,
+			OnError: snowman.ErrorFn(func(_ *snowman.Msg, _ snowman.Dialogue, err error) {
+				errs <- err
+			}),
+		})
+
+		ui.in <- userMsg("alice", "panic")
+
+		var err error
+		select {
+		case err = <-errs:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("OnError not invoked for the panic")
+		}
+
+		var pe *snowman.PanicError
+		if !errors.As(err, &pe) {
+			t.Fatalf("OnError want *PanicError, got %v", err)
+		}
+		if pe.Value != "boom" {
+			t.Errorf("PanicError want value 'boom', got %v", pe.Value)
+		}
+		if !bytes.Contains(pe.Stack, []byte("errors_test.go")) {
+			t.Errorf("PanicError want stack of the handler, got:\n%s", pe.Stack)
+		}
+
+		// the worker must survive the panic.
+		ui.in <- userMsg("alice", "hello")
+		expectSaid(t, ui, "alice", "ok")
+	})
+
+	t.Run("PanickingErrorHandler", func(t *testing.T) {
+		ui := newTestUI()
+		startBot(t, &snowman.Bot{
+			UI:      ui,
+			Handler: failingHandler,
+			OnError: snowman.ErrorFn(func(*snowman.Msg, snowman.Dialogue, error) {
+				panic("error handler broke")
+			}),
+		})
+
+		ui.in <- userMsg("alice", "fail")
+		ui.in <- userMsg("alice", "panic")
+		ui.in <- userMsg("alice", "hello")
+		expectSaid(t, ui, "alice", "ok")
+	})
+}
+
+func TestErrorReporter(t *testing.T) {
+	t.Parallel()
+
+	ui := newTestUI()
+	er := &snowman.ErrorReporter{Apology: "sorry, something went wrong"}
+	startBot(t, &snowman.Bot{UI: ui, Handler: failingHandler, OnError: er})
+
+	for _, body := range []string{"fail", "panic", "hello"} {
+		ui.in <- userMsg("alice", body)
+	}
+	expectSaid(t, ui, "alice", "sorry, something went wrong")
+	expectSaid(t, ui, "alice", "sorry, something went wrong")
+	expectSaid(t, ui, "alice", "ok")
+
+	if er.Failures() != 2 {
+		t.Errorf("Failures() want 2, got %d", er.Failures())
+	}
+	if er.Panics() != 1 {
+		t.Errorf("Panics() want 1, got %d", er.Panics())
+	}
+}
+
+// failingHandler panics for 'panic', fails for 'fail' and says 'ok' for any
+// other message.
+var failingHandler = snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+	switch msg.Body {
+	case "panic":
+		panic("boom")
+
+	case "fail":
+		return errors.New("failed")
+	}
+	return di.Say(msg.Context(), "ok")
+})
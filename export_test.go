@@ -0,0 +1,8 @@
+package snowman
+
+// Unexported functions used by the tests in snowman_test.
+var (
+	RenderText  = renderText
+	PickChoice  = pickChoice
+	SlackBlocks = slackBlocks
+)
@@ -0,0 +1,107 @@
+// Package flow provides a snowman.Handler for running multi-turn conversation
+// flows (e.g., waiting for a confirmation) modelled as state machines on top of
+// the dialogue state.
+package flow
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spy16/snowman"
+)
+
+const defaultMaxRetries = 2
+
+// Flow represents a multi-turn conversation modelled as a state machine. A
+// flow is started when a message with one of the Trigger intents is received
+// and moves between states based on the intents of the subsequent messages.
+type Flow struct {
+	Name       string   `json:"name" yaml:"name"`
+	Trigger    []string `json:"trigger" yaml:"trigger"`
+	Cancel     []string `json:"cancel" yaml:"cancel"`
+	Start      string   `json:"start" yaml:"start"`
+	States     []State  `json:"states" yaml:"states"`
+	MaxRetries int      `json:"max_retries" yaml:"max_retries"`
+
+	// Cancelled is said to the user when the flow is cancelled either
+	// explicitly or because of too many unexpected replies.
+	Cancelled string `json:"cancelled" yaml:"cancelled"`
+
+	states map[string]*State
+}
+
+// State represents a single step in a flow. Prompt is said to the user when
+// the flow enters the state and Reprompt is said when the reply does not
+// match any of the expected intents. A state without any expectations is a
+// terminal state and the flow ends once it is entered.
+type State struct {
+	Name     string `json:"name" yaml:"name"`
+	Prompt   string `json:"prompt" yaml:"prompt"`
+	Reprompt string `json:"reprompt" yaml:"reprompt"`
+
+	// Expect maps the intent tags expected in this state to the name of
+	// the state to transition to.
+	Expect map[string]string `json:"expect" yaml:"expect"`
+
+	// Slots lists the intent context keys that should be saved into the
+	// dialogue slots when transitioning out of this state.
+	Slots []string `json:"slots" yaml:"slots"`
+
+	// OnEnter, if set, is invoked with the message that caused the flow
+	// to enter this state, after the prompt is said.
+	OnEnter snowman.Handler `json:"-" yaml:"-"`
+}
+
+func (st *State) isTerminal() bool { return len(st.Expect) == 0 }
+
+func (f *Flow) init() error {
+	if f.Name == "" {
+		return errors.New("flow name must not be empty")
+	}
+
+	if f.MaxRetries == 0 {
+		f.MaxRetries = defaultMaxRetries
+	}
+
+	f.states = map[string]*State{}
+	for i := range f.States {
+		st := &f.States[i]
+		if _, dup := f.states[st.Name]; dup {
+			return fmt.Errorf("flow '%s': duplicate state '%s'", f.Name, st.Name)
+		}
+		f.states[st.Name] = st
+	}
+
+	if _, found := f.states[f.Start]; !found {
+		return fmt.Errorf("flow '%s': start state '%s' is not defined", f.Name, f.Start)
+	}
+
+	for _, st := range f.states {
+		for tag, next := range st.Expect {
+			if _, found := f.states[next]; !found {
+				return fmt.Errorf("flow '%s': state '%s' transitions to undefined state '%s' on '%s'",
+					f.Name, st.Name, next, tag)
+			}
+		}
+	}
+	return nil
+}
+
+func (f *Flow) isTriggeredBy(msg *snowman.Msg) bool {
+	return matchesAny(msg, f.Trigger)
+}
+
+func (f *Flow) isCancelledBy(msg *snowman.Msg) bool {
+	return matchesAny(msg, f.Cancel)
+}
+
+func matchesAny(msg *snowman.Msg, tags []string) bool {
+	for _, intent := range msg.Intents {
+		for _, tag := range tags {
+			if intent.Tag == tag {
+				return true
+			}
+		}
+	}
+	return false
+}
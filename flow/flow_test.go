@@ -0,0 +1,133 @@
+package flow_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/spy16/snowman"
+	"github.com/spy16/snowman/flow"
+)
+
+func TestRunner(t *testing.T) {
+	t.Parallel()
+
+	var booked interface{}
+	order := flow.Flow{
+		Name:      "order",
+		Trigger:   []string{"order"},
+		Cancel:    []string{"stop"},
+		Start:     "size",
+		Cancelled: "cancelled",
+		States: []flow.State{
+			{
+				Name:     "size",
+				Prompt:   "which size?",
+				Reprompt: "small or large?",
+				Expect:   map[string]string{"size": "confirm"},
+				Slots:    []string{"size"},
+			},
+			{
+				Name:   "confirm",
+				Prompt: "sure?",
+				Expect: map[string]string{"yes": "done", "no": "size"},
+			},
+			{
+				Name:   "done",
+				Prompt: "ordered",
+				OnEnter: snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+					booked, _ = di.Slot("size")
+					return nil
+				}),
+			},
+		},
+	}
+
+	runner, err := flow.New(snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+		return di.Say(msg.Context(), "no flow")
+	}), order)
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	table := []struct {
+		title  string
+		inputs []string
+		want   []string
+	}{
+		{
+			title:  "Complete",
+			inputs: []string{"order", "size:large", "yes", "yes"},
+			want:   []string{"which size?", "sure?", "ordered", "no flow"},
+		},
+		{
+			title:  "Reprompt",
+			inputs: []string{"order", "hello", "size:small", "no", "size:large"},
+			want:   []string{"which size?", "small or large?", "sure?", "which size?", "sure?"},
+		},
+		{
+			title:  "TooManyRetries",
+			inputs: []string{"order", "a", "b", "c", "d"},
+			want:   []string{"which size?", "small or large?", "small or large?", "cancelled", "no flow"},
+		},
+		{
+			title:  "Cancel",
+			inputs: []string{"order", "stop", "yes"},
+			want:   []string{"which size?", "cancelled", "no flow"},
+		},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.title, func(t *testing.T) {
+			ui := &scriptedUI{inputs: tt.inputs}
+			bot := snowman.Bot{UI: ui, Handler: runner, Logger: snowman.NoOpLogger{}}
+			bot.Use(tagByBody)
+
+			if err := bot.Run(context.Background()); err != nil {
+				t.Fatalf("Run() unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(ui.said, tt.want) {
+				t.Errorf("Run() want replies %q, got %q", tt.want, ui.said)
+			}
+		})
+	}
+
+	if booked != "large" {
+		t.Errorf("OnEnter() want slot size=large, got %v", booked)
+	}
+}
+
+// tagByBody tags messages of the form 'tag' or 'tag:value' with an intent
+// having 'value' as the context value named after the tag.
+func tagByBody(next snowman.Handler) snowman.Handler {
+	return snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+		intent := snowman.Intent{Tag: msg.Body, Confidence: 1}
+		for i, c := range msg.Body {
+			if c == ':' {
+				intent.Tag = msg.Body[:i]
+				intent.Context = map[string]interface{}{intent.Tag: msg.Body[i+1:]}
+				break
+			}
+		}
+		msg.Intents = append(msg.Intents, intent)
+		return next.Handle(msg, di)
+	})
+}
+
+type scriptedUI struct {
+	inputs []string
+	said   []string
+}
+
+func (ui *scriptedUI) Say(_ context.Context, msg snowman.Msg) error {
+	ui.said = append(ui.said, msg.Body)
+	return nil
+}
+
+func (ui *scriptedUI) Listen(_ context.Context, receive func(msg snowman.Msg)) error {
+	for _, input := range ui.inputs {
+		receive(snowman.Msg{From: snowman.User{ID: "user"}, Body: input})
+	}
+	return nil
+}
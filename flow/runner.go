@@ -0,0 +1,154 @@
+package flow
+
+import (
+	"fmt"
+
+	"github.com/spy16/snowman"
+)
+
+var _ snowman.Handler = (*Runner)(nil)
+
+// Dialogue state keys used to track the progress of a flow.
+const (
+	keyFlow    = "flow.name"
+	keyState   = "flow.state"
+	keyRetries = "flow.retries"
+)
+
+// New returns a Runner for the given flows. Messages that are not part of any
+// flow are passed on to next. If next is nil, snowman.NotUnderstood() is used.
+func New(next snowman.Handler, flows ...Flow) (*Runner, error) {
+	if next == nil {
+		next = snowman.NotUnderstood()
+	}
+
+	r := &Runner{
+		next:  next,
+		flows: map[string]*Flow{},
+	}
+	for i := range flows {
+		f := flows[i]
+		if err := f.init(); err != nil {
+			return nil, err
+		}
+
+		if _, dup := r.flows[f.Name]; dup {
+			return nil, fmt.Errorf("duplicate flow '%s'", f.Name)
+		}
+		r.flows[f.Name] = &f
+		r.order = append(r.order, &f)
+	}
+	return r, nil
+}
+
+// Runner implements snowman.Handler and runs the flows on top of the dialogue
+// state. At most one flow is active in a dialogue at any time.
+type Runner struct {
+	next  snowman.Handler
+	flows map[string]*Flow
+	order []*Flow
+}
+
+// Handle advances the active flow of the dialogue using the message, starts
+// a new flow if the message triggers one, or passes the message to the next
+// handler otherwise.
+func (r *Runner) Handle(msg *snowman.Msg, di snowman.Dialogue) error {
+	name, _ := snowman.GetString(di, keyFlow)
+	f, active := r.flows[name]
+	if !active {
+		for _, f := range r.order {
+			if f.isTriggeredBy(msg) {
+				return r.start(f, msg, di)
+			}
+		}
+		return r.next.Handle(msg, di)
+	}
+
+	if f.isCancelledBy(msg) {
+		return r.cancel(f, msg, di)
+	}
+
+	stateName, _ := snowman.GetString(di, keyState)
+	st, found := f.states[stateName]
+	if !found {
+		// flow definition changed since the dialogue entered the state.
+		r.end(di)
+		return r.next.Handle(msg, di)
+	}
+
+	for _, intent := range msg.Intents {
+		nextState, expected := st.Expect[intent.Tag]
+		if !expected {
+			continue
+		}
+
+		for _, slot := range st.Slots {
+			if val, found := intent.Context[slot]; found {
+				di.SetSlot(slot, val)
+			}
+		}
+		return r.enter(f, f.states[nextState], msg, di)
+	}
+
+	retries, _ := snowman.GetInt(di, keyRetries)
+	if retries >= f.MaxRetries {
+		return r.cancel(f, msg, di)
+	}
+	di.Set(keyRetries, retries+1)
+
+	reprompt := st.Reprompt
+	if reprompt == "" {
+		reprompt = st.Prompt
+	}
+	return di.Say(msg.Context(), reprompt)
+}
+
+// Active returns the name of the flow currently active in the dialogue or
+// empty string if none is active.
+func Active(di snowman.Dialogue) string {
+	name, _ := snowman.GetString(di, keyFlow)
+	return name
+}
+
+func (r *Runner) start(f *Flow, msg *snowman.Msg, di snowman.Dialogue) error {
+	di.ClearSlots()
+	di.Set(keyFlow, f.Name)
+	return r.enter(f, f.states[f.Start], msg, di)
+}
+
+func (r *Runner) enter(f *Flow, st *State, msg *snowman.Msg, di snowman.Dialogue) error {
+	di.Set(keyState, st.Name)
+	di.Set(keyRetries, 0)
+
+	if st.Prompt != "" {
+		if err := di.Say(msg.Context(), st.Prompt); err != nil {
+			return err
+		}
+	}
+
+	if st.OnEnter != nil {
+		if err := st.OnEnter.Handle(msg, di); err != nil {
+			return err
+		}
+	}
+
+	if st.isTerminal() {
+		r.end(di)
+	}
+	return nil
+}
+
+func (r *Runner) cancel(f *Flow, msg *snowman.Msg, di snowman.Dialogue) error {
+	r.end(di)
+	if f.Cancelled == "" {
+		return nil
+	}
+	return di.Say(msg.Context(), f.Cancelled)
+}
+
+func (r *Runner) end(di snowman.Dialogue) {
+	di.Delete(keyFlow)
+	di.Delete(keyState)
+	di.Delete(keyRetries)
+	di.ClearSlots()
+}
@@ -3,6 +3,7 @@ module github.com/spy16/snowman
 go 1.14
 
 require (
+	github.com/gorilla/websocket v1.4.2
 	github.com/sirupsen/logrus v1.6.0
 	github.com/slack-go/slack v0.7.4
 	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c
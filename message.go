@@ -3,19 +3,58 @@ package snowman
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 )
 
 // Msg represents a message from the user/bot. Msg can contain additional
 // context in terms of intents that may be used by handler to generate response.
+// Outgoing messages can carry rich content (blocks, attachments, buttons and
+// quick replies) which each UI renders as best as it can.
 type Msg struct {
 	ctx context.Context
 
+	// done, if set by the UI, is invoked once the bot is done handling the
+	// message.
+	done func()
+
+	ID      string    `json:"id,omitempty"`
 	At      time.Time `json:"at"`
 	To      User      `json:"to"`
 	From    User      `json:"from"`
 	Body    string    `json:"body"`
 	Intents []Intent  `json:"intents"`
+
+	// Channel identifies the conversation (DM, group or channel) on the UI
+	// the message was received in and ChannelType tells which kind it is.
+	// Thread identifies the thread within the channel the message is part
+	// of (empty if not threaded).
+	Channel     string      `json:"channel,omitempty"`
+	ChannelType ChannelType `json:"channel_type,omitempty"`
+	Thread      string      `json:"thread,omitempty"`
+
+	// Mentions lists the users mentioned in the message (other than the bot
+	// itself when the message is addressed to it).
+	Mentions []User `json:"mentions,omitempty"`
+
+	// Raw is the platform specific event the message was created from (e.g.,
+	// *slack.MessageEvent for SlackUI).
+	Raw interface{} `json:"-"`
+
+	// Origin is the name of the UI the message came from (or should be sent
+	// to). This is set by MultiUI.
+	Origin string `json:"origin,omitempty"`
+
+	// Markdown indicates that Body and Blocks use markdown formatting.
+	Markdown     bool         `json:"markdown,omitempty"`
+	Blocks       []string     `json:"blocks,omitempty"`
+	Attachments  []Attachment `json:"attachments,omitempty"`
+	Buttons      []Button     `json:"buttons,omitempty"`
+	QuickReplies []QuickReply `json:"quick_replies,omitempty"`
+
+	// Payload is set on the messages generated by user interactions such as
+	// clicking a button or choosing a quick reply.
+	Payload string `json:"payload,omitempty"`
 }
 
 // Context returns the context associated with the message.
@@ -23,11 +62,108 @@ func (m Msg) Context() context.Context { return m.ctx }
 
 func (m Msg) String() string { return fmt.Sprintf("Msg<to=@%s,from=@%s>", m.To.ID, m.From.ID) }
 
+// finish signals the UI that the bot is done with the message, whether it was
+// handled or dropped.
+func (m Msg) finish() {
+	if m.done != nil {
+		m.done()
+	}
+}
+
+// inflight tracks the messages delivered to the bot by a UI until the bot is
+// done with them, so that the UI can keep its connections open for replies
+// while the bot drains the messages during shutdown.
+type inflight struct {
+	mu     sync.Mutex
+	wg     sync.WaitGroup
+	closed bool
+}
+
+// track marks the message to be tracked. Returns false if the tracker has
+// been closed, in which case the message must not be delivered.
+func (in *inflight) track(msg *Msg) bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	if in.closed {
+		return false
+	}
+	in.wg.Add(1)
+
+	done := msg.done
+	msg.done = func() {
+		if done != nil {
+			done()
+		}
+		in.wg.Done()
+	}
+	return true
+}
+
+// closeThen stops tracking new messages and invokes fn in the background once
+// the bot is done with all the tracked messages.
+func (in *inflight) closeThen(fn func()) {
+	in.mu.Lock()
+	in.closed = true
+	in.mu.Unlock()
+
+	go func() {
+		in.wg.Wait()
+		fn()
+	}()
+}
+
+// ChannelType represents the kind of conversation a message was sent in.
+type ChannelType string
+
+// Channel types supported.
+const (
+	ChannelDirect  ChannelType = "direct"
+	ChannelGroup   ChannelType = "group"
+	ChannelPublic  ChannelType = "channel"
+	ChannelPrivate ChannelType = "private"
+)
+
+// Attachment types supported.
+const (
+	AttachmentImage = "image"
+	AttachmentFile  = "file"
+)
+
+// Attachment represents an image or a file attached to a message.
+type Attachment struct {
+	Type  string `json:"type"`
+	URL   string `json:"url"`
+	Name  string `json:"name,omitempty"`
+	Title string `json:"title,omitempty"`
+}
+
+// Button represents an action the user can take on a message. Clicking the
+// button sends a message with the Payload back to the bot. If URL is set, the
+// button opens the URL instead (where supported). Style can be 'primary' or
+// 'danger'.
+type Button struct {
+	Label   string `json:"label"`
+	Payload string `json:"payload,omitempty"`
+	URL     string `json:"url,omitempty"`
+	Style   string `json:"style,omitempty"`
+}
+
+// QuickReply represents a suggested reply. Choosing the quick reply sends a
+// message with the Label as the body and the Payload back to the bot.
+type QuickReply struct {
+	Label   string `json:"label"`
+	Payload string `json:"payload,omitempty"`
+}
+
 // User represents a user that is interacting with snowman.
 type User struct {
-	ID      string                 `json:"id"`
-	Name    string                 `json:"name"`
-	Attribs map[string]interface{} `json:"attribs"`
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	DisplayName string                 `json:"display_name,omitempty"`
+	Email       string                 `json:"email,omitempty"`
+	TimeZone    string                 `json:"time_zone,omitempty"`
+	Attribs     map[string]interface{} `json:"attribs"`
 }
 
 func (u User) String() string { return fmt.Sprintf("User<ID=@%s>", u.ID) }
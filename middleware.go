@@ -0,0 +1,48 @@
+package snowman
+
+// Middleware wraps a Handler to add behaviour before and/or after it. This
+// follows the familiar net/http middleware pattern.
+type Middleware func(next Handler) Handler
+
+// Chain wraps the handler with the given middlewares. Middlewares are applied
+// such that the first one in the list is the outermost and sees the message
+// first.
+func Chain(h Handler, mws ...Middleware) Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// Use appends the given middlewares to the middleware chain of the bot. The
+// chain is applied around the Handler in the order middlewares were added.
+// Use must be called before Run.
+func (bot *Bot) Use(mws ...Middleware) {
+	bot.middlewares = append(bot.middlewares, mws...)
+}
+
+// LogMessages returns a middleware that logs every message received by the
+// bot along with the intents attached to it by the handlers.
+func LogMessages(logger Logger) Middleware {
+	return func(next Handler) Handler {
+		return Fn(func(msg *Msg, di Dialogue) error {
+			logger.Debugf("received %s (dialogue=%s): %q", msg, di.ID(), msg.Body)
+			err := next.Handle(msg, di)
+			if len(msg.Intents) > 0 {
+				logger.Debugf("intents for %s: %v", msg, msg.Intents)
+			}
+			return err
+		})
+	}
+}
+
+// ShowTyping returns a middleware that shows a typing indicator to the user
+// before invoking the next handler, for UIs that support it.
+func ShowTyping() Middleware {
+	return func(next Handler) Handler {
+		return Fn(func(msg *Msg, di Dialogue) error {
+			_ = di.Typing(msg.Context())
+			return next.Handle(msg, di)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package snowman_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/spy16/snowman"
+)
+
+func TestChain(t *testing.T) {
+	t.Parallel()
+
+	var calls []string
+	h := snowman.Chain(snowman.Fn(func(*snowman.Msg, snowman.Dialogue) error {
+		calls = append(calls, "handler")
+		return nil
+	}), tracing("a", &calls), tracing("b", &calls))
+
+	if err := h.Handle(&snowman.Msg{}, nil); err != nil {
+		t.Fatalf("Handle() unexpected error: %v", err)
+	}
+
+	want := []string{"a>", "b>", "handler", "<b", "<a"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("want calls %v, got %v", want, calls)
+	}
+}
+
+func TestBot_Use(t *testing.T) {
+	t.Parallel()
+
+	ui := newTestUI()
+	logger := &recordingLogger{}
+
+	var calls []string
+	bot := &snowman.Bot{
+		UI:     ui,
+		Logger: snowman.NoOpLogger{},
+		Handler: snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+			calls = append(calls, "handler")
+			return di.Say(msg.Context(), fmt.Sprint(msg.Intents))
+		}),
+	}
+	bot.Use(tracing("a", &calls), snowman.LogMessages(logger))
+	bot.Use(tracing("b", &calls), func(next snowman.Handler) snowman.Handler {
+		return snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+			msg.Intents = append(msg.Intents, snowman.Intent{Tag: "greet"})
+			return next.Handle(msg, di)
+		})
+	})
+	stop := startBot(t, bot)
+
+	ui.in <- userMsg("alice", "hello")
+	expectSaid(t, ui, "alice", "[greet()]")
+	_ = stop()
+
+	want := []string{"a>", "b>", "handler", "<b", "<a"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("want calls %v, got %v", want, calls)
+	}
+
+	// the intents attached by the inner middlewares are logged once the
+	// message is handled.
+	logs := logger.String()
+	if !strings.Contains(logs, `"hello"`) || !strings.Contains(logs, "greet()") {
+		t.Errorf("want message and intents logged, got:\n%s", logs)
+	}
+}
+
+// tracing returns a middleware that records its name on calls before and after
+// invoking the next handler.
+func tracing(name string, calls *[]string) snowman.Middleware {
+	return func(next snowman.Handler) snowman.Handler {
+		return snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+			*calls = append(*calls, name+">")
+			err := next.Handle(msg, di)
+			*calls = append(*calls, "<"+name)
+			return err
+		})
+	}
+}
+
+// recordingLogger records the debug, warning and error logs.
+type recordingLogger struct {
+	snowman.NoOpLogger
+
+	mu   sync.Mutex
+	logs []string
+}
+
+func (rl *recordingLogger) Debugf(msg string, args ...interface{}) { rl.record(msg, args...) }
+func (rl *recordingLogger) Warnf(msg string, args ...interface{})  { rl.record(msg, args...) }
+func (rl *recordingLogger) Errorf(msg string, args ...interface{}) { rl.record(msg, args...) }
+
+func (rl *recordingLogger) record(msg string, args ...interface{}) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.logs = append(rl.logs, fmt.Sprintf(msg, args...))
+}
+
+func (rl *recordingLogger) String() string {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return strings.Join(rl.logs, "\n")
+}
@@ -0,0 +1,196 @@
+// Package cron implements parsing of standard 5-field cron expressions and
+// computing their activation times.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var fields = []struct {
+	name     string
+	min, max int
+}{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day-of-month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12},
+	{name: "day-of-week", min: 0, max: 6},
+}
+
+// Parse parses a cron expression of the form 'minute hour day-of-month month
+// day-of-week'. Each field supports '*', values, ranges (a-b), steps (*/n or
+// a-b/n) and comma separated lists of these. Descriptors like '@daily' and
+// '@hourly' are also supported. Sunday is 0 (7 is accepted as an alias).
+func Parse(spec string) (*Schedule, error) {
+	expr := strings.TrimSpace(spec)
+	if d, found := descriptors[strings.ToLower(expr)]; found {
+		expr = d
+	}
+
+	parts := strings.Fields(expr)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("cron: expected %d fields in '%s', got %d", len(fields), spec, len(parts))
+	}
+
+	var sets [5]uint64
+	for i, part := range parts {
+		f := fields[i]
+		max := f.max
+		if i == 4 {
+			max = 7
+		}
+
+		set, err := parseField(part, f.min, max)
+		if err != nil {
+			return nil, fmt.Errorf("cron: invalid %s field '%s': %v", f.name, part, err)
+		}
+		sets[i] = set
+	}
+
+	if sets[4]&(1<<7) != 0 {
+		sets[4] = (sets[4] | 1) &^ (1 << 7)
+	}
+
+	return &Schedule{
+		spec:       spec,
+		minutes:    sets[0],
+		hours:      sets[1],
+		days:       sets[2],
+		months:     sets[3],
+		weekdays:   sets[4],
+		anyDay:     parts[2] == "*",
+		anyWeekday: parts[4] == "*",
+	}, nil
+}
+
+// Schedule represents a parsed cron expression.
+type Schedule struct {
+	spec       string
+	minutes    uint64
+	hours      uint64
+	days       uint64
+	months     uint64
+	weekdays   uint64
+	anyDay     bool
+	anyWeekday bool
+}
+
+// Next returns the first activation time strictly after t. Returns zero time
+// if the schedule never activates (e.g., '0 0 31 2 *').
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	// 5 years is enough to find a match for any valid expression (leap
+	// days included).
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if !has(s.months, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if !has(s.hours, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if !has(s.minutes, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) String() string { return s.spec }
+
+// matchDay follows the standard cron semantics: if both day-of-month and
+// day-of-week are restricted, a day matching either of them is accepted.
+func (s *Schedule) matchDay(t time.Time) bool {
+	dom := has(s.days, t.Day())
+	dow := has(s.weekdays, int(t.Weekday()))
+
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return dow
+	case s.anyWeekday:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+func parseField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(field, ",") {
+		rangePart, step := item, 1
+		if i := strings.IndexByte(item, '/'); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step '%s'", item[i+1:])
+			}
+			rangePart, step = item[:i], n
+		}
+
+		lo, hi := min, max
+		if rangePart != "*" {
+			var err error
+			if i := strings.IndexByte(rangePart, '-'); i >= 0 {
+				if lo, err = parseValue(rangePart[:i], min, max); err != nil {
+					return 0, err
+				}
+				if hi, err = parseValue(rangePart[i+1:], min, max); err != nil {
+					return 0, err
+				}
+				if lo > hi {
+					return 0, fmt.Errorf("invalid range '%s'", rangePart)
+				}
+			} else {
+				if lo, err = parseValue(rangePart, min, max); err != nil {
+					return 0, err
+				}
+				if step == 1 {
+					hi = lo
+				}
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+func parseValue(s string, min, max int) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("'%s' is not a number", s)
+	} else if v < min || v > max {
+		return 0, fmt.Errorf("%d is not in range [%d, %d]", v, min, max)
+	}
+	return v, nil
+}
+
+func has(set uint64, v int) bool { return set&(1<<uint(v)) != 0 }
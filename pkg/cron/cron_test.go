@@ -0,0 +1,66 @@
+package cron_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spy16/snowman/pkg/cron"
+)
+
+func TestParse_Invalid(t *testing.T) {
+	t.Parallel()
+
+	specs := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	}
+
+	for _, spec := range specs {
+		if _, err := cron.Parse(spec); err == nil {
+			t.Errorf("Parse('%s') expected error, got nil", spec)
+		}
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	t.Parallel()
+
+	// Wednesday
+	base := time.Date(2021, 3, 10, 10, 30, 15, 0, time.UTC)
+
+	table := []struct {
+		spec string
+		want time.Time
+	}{
+		{spec: "* * * * *", want: time.Date(2021, 3, 10, 10, 31, 0, 0, time.UTC)},
+		{spec: "*/15 * * * *", want: time.Date(2021, 3, 10, 10, 45, 0, 0, time.UTC)},
+		{spec: "0 9 * * *", want: time.Date(2021, 3, 11, 9, 0, 0, 0, time.UTC)},
+		{spec: "@hourly", want: time.Date(2021, 3, 10, 11, 0, 0, 0, time.UTC)},
+		{spec: "0 9 * * 1-5", want: time.Date(2021, 3, 11, 9, 0, 0, 0, time.UTC)},
+		{spec: "0 0 * * 0", want: time.Date(2021, 3, 14, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 * * 7", want: time.Date(2021, 3, 14, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 12 1,15 * *", want: time.Date(2021, 3, 15, 12, 0, 0, 0, time.UTC)},
+		{spec: "0 0 1 * 5", want: time.Date(2021, 3, 12, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 29 2 *", want: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 31 2 *", want: time.Time{}},
+	}
+
+	for _, tt := range table {
+		sched, err := cron.Parse(tt.spec)
+		if err != nil {
+			t.Fatalf("Parse('%s') unexpected error: %v", tt.spec, err)
+		}
+
+		if got := sched.Next(base); !got.Equal(tt.want) {
+			t.Errorf("Next() for '%s' want %v, got %v", tt.spec, tt.want, got)
+		}
+	}
+}
@@ -13,7 +13,8 @@ import (
 	"github.com/spy16/snowman"
 )
 
-// New returns a new regular-expressions based intent classifier.
+// New returns a new regular-expressions based intent classifier. next can be
+// nil if the classifier is only going to be used as a Middleware.
 func New(next snowman.Handler) *IntentClassifier {
 	return &IntentClassifier{invoke: next}
 }
@@ -28,11 +29,25 @@ type IntentClassifier struct {
 // Handle iterates through all the registered patterns and tags the message
 // with the matching intents.
 func (re *IntentClassifier) Handle(msg *snowman.Msg, di snowman.Dialogue) error {
+	re.classify(msg)
+	return re.invoke.Handle(msg, di)
+}
+
+// Middleware can be used with snowman.Bot.Use to tag the messages with the
+// matching intents before passing them to the next handler in the chain. The
+// handler passed to New is ignored in this mode.
+func (re *IntentClassifier) Middleware(next snowman.Handler) snowman.Handler {
+	return snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+		re.classify(msg)
+		return next.Handle(msg, di)
+	})
+}
+
+func (re *IntentClassifier) classify(msg *snowman.Msg) {
 	for _, pattern := range re.entries {
 		intents := pattern.match(msg.Body)
 		msg.Intents = append(msg.Intents, intents...)
 	}
-	return re.invoke.Handle(msg, di)
 }
 
 // Add adds pattern entries to the intent-classifier.
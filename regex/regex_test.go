@@ -0,0 +1,36 @@
+package regex_test
+
+import (
+	"testing"
+
+	"github.com/spy16/snowman"
+	"github.com/spy16/snowman/regex"
+)
+
+func TestIntentClassifier_Middleware(t *testing.T) {
+	t.Parallel()
+
+	re := regex.New(nil)
+	err := re.Add(regex.Entry{
+		Intent:   snowman.Intent{Tag: "weather"},
+		Patterns: []string{`weather in (?P<city>\w+)`},
+	})
+	if err != nil {
+		t.Fatalf("Add() unexpected error: %v", err)
+	}
+
+	var got []snowman.Intent
+	h := snowman.Chain(snowman.Fn(func(msg *snowman.Msg, _ snowman.Dialogue) error {
+		got = msg.Intents
+		return nil
+	}), re.Middleware)
+
+	if err := h.Handle(&snowman.Msg{Body: "Weather in Paris"}, nil); err != nil {
+		t.Fatalf("Handle() unexpected error: %v", err)
+	}
+
+	// the intents must be tagged before the next handler runs.
+	if len(got) != 1 || got[0].Tag != "weather" || got[0].Context["city"] != "paris" {
+		t.Errorf("want 'weather' intent with city 'paris', got %v", got)
+	}
+}
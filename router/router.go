@@ -0,0 +1,123 @@
+package router
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/spy16/snowman"
+)
+
+var _ snowman.Handler = (*Router)(nil)
+
+// New returns a new intent router. fallback is invoked for messages that do
+// not match any of the registered routes. If fallback is nil, the handler
+// returned by snowman.NotUnderstood() is used.
+func New(fallback snowman.Handler) *Router {
+	if fallback == nil {
+		fallback = snowman.NotUnderstood()
+	}
+	return &Router{fallback: fallback}
+}
+
+// Router implements snowman.Handler and dispatches messages to handlers based
+// on the tags of the intents attached to the message. Intents are considered
+// in the order of decreasing confidence and routes are matched in the order
+// they were registered.
+type Router struct {
+	// MinConfidence is the confidence threshold applied to routes that do
+	// not specify one explicitly.
+	MinConfidence float64
+
+	fallback snowman.Handler
+	routes   []*Route
+}
+
+// Route represents a mapping from intent tags to a handler.
+type Route struct {
+	pattern   string
+	match     func(tag string) bool
+	handler   snowman.Handler
+	threshold *float64
+}
+
+// MinConfidence sets the minimum confidence an intent must have for this route
+// to be selected. This overrides the Router.MinConfidence value.
+func (r *Route) MinConfidence(c float64) *Route {
+	r.threshold = &c
+	return r
+}
+
+func (r *Route) String() string { return fmt.Sprintf("Route<%s>", r.pattern) }
+
+// Tag registers the handler for intents with exactly the given tag.
+func (rt *Router) Tag(tag string, h snowman.Handler) *Route {
+	return rt.add(tag, h, func(t string) bool { return t == tag })
+}
+
+// Prefix registers the handler for intents with tags having the given prefix.
+func (rt *Router) Prefix(prefix string, h snowman.Handler) *Route {
+	return rt.add(prefix+"*", h, func(t string) bool { return strings.HasPrefix(t, prefix) })
+}
+
+// Glob registers the handler for intents with tags matching the given glob
+// pattern. Pattern syntax is same as path.Match. Glob panics if the pattern
+// is malformed.
+func (rt *Router) Glob(pattern string, h snowman.Handler) *Route {
+	if _, err := path.Match(pattern, ""); err != nil {
+		panic(fmt.Sprintf("router: invalid glob pattern '%s': %v", pattern, err))
+	}
+
+	return rt.add(pattern, h, func(t string) bool {
+		matched, _ := path.Match(pattern, t)
+		return matched
+	})
+}
+
+// Handle finds the first route matching the intents of the message and invokes
+// the handler. The intent that matched is moved to the front of msg.Intents so
+// that the handler can simply use msg.Intents[0].
+func (rt *Router) Handle(msg *snowman.Msg, di snowman.Dialogue) error {
+	intents := make([]snowman.Intent, len(msg.Intents))
+	copy(intents, msg.Intents)
+	sort.SliceStable(intents, func(i, j int) bool {
+		return intents[i].Confidence > intents[j].Confidence
+	})
+
+	for i, intent := range intents {
+		for _, r := range rt.routes {
+			if !r.match(intent.Tag) || intent.Confidence < rt.threshold(r) {
+				continue
+			}
+
+			copy(intents[1:i+1], intents[0:i])
+			intents[0] = intent
+			msg.Intents = intents
+			return r.handler.Handle(msg, di)
+		}
+	}
+
+	return rt.fallback.Handle(msg, di)
+}
+
+func (rt *Router) add(pattern string, h snowman.Handler, match func(tag string) bool) *Route {
+	if h == nil {
+		panic("router: nil handler")
+	}
+
+	r := &Route{
+		pattern: pattern,
+		match:   match,
+		handler: h,
+	}
+	rt.routes = append(rt.routes, r)
+	return r
+}
+
+func (rt *Router) threshold(r *Route) float64 {
+	if r.threshold != nil {
+		return *r.threshold
+	}
+	return rt.MinConfidence
+}
@@ -0,0 +1,90 @@
+package router_test
+
+import (
+	"testing"
+
+	"github.com/spy16/snowman"
+	"github.com/spy16/snowman/router"
+)
+
+func TestRouter_Handle(t *testing.T) {
+	t.Parallel()
+
+	var got string
+	record := func(name string) snowman.Handler {
+		return snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+			got = name
+			return nil
+		})
+	}
+
+	rt := router.New(record("fallback"))
+	rt.MinConfidence = 0.5
+	rt.Tag("greetings", record("greetings"))
+	rt.Prefix("confirm ", record("confirm")).MinConfidence(0.9)
+	rt.Glob("weather.*", record("weather"))
+
+	table := []struct {
+		title   string
+		intents []snowman.Intent
+		want    string
+		wantTag string
+	}{
+		{
+			title: "NoIntents",
+			want:  "fallback",
+		},
+		{
+			title:   "ExactTag",
+			intents: []snowman.Intent{{Tag: "greetings", Confidence: 1}},
+			want:    "greetings",
+			wantTag: "greetings",
+		},
+		{
+			title:   "ExactTagBelowThreshold",
+			intents: []snowman.Intent{{Tag: "greetings", Confidence: 0.2}},
+			want:    "fallback",
+		},
+		{
+			title:   "PrefixWithRouteThreshold",
+			intents: []snowman.Intent{{Tag: "confirm yes", Confidence: 0.7}},
+			want:    "fallback",
+		},
+		{
+			title: "Glob",
+			intents: []snowman.Intent{
+				{Tag: "unknown", Confidence: 0.6},
+				{Tag: "weather.today", Confidence: 0.8},
+			},
+			want:    "weather",
+			wantTag: "weather.today",
+		},
+		{
+			title: "HighestConfidenceFirst",
+			intents: []snowman.Intent{
+				{Tag: "greetings", Confidence: 0.6},
+				{Tag: "confirm no", Confidence: 0.95},
+			},
+			want:    "confirm",
+			wantTag: "confirm no",
+		},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.title, func(t *testing.T) {
+			got = ""
+			msg := &snowman.Msg{Intents: tt.intents}
+			if err := rt.Handle(msg, nil); err != nil {
+				t.Fatalf("Handle() unexpected error: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Handle() want handler '%s', got '%s'", tt.want, got)
+			}
+
+			if tt.wantTag != "" && msg.Intents[0].Tag != tt.wantTag {
+				t.Errorf("Handle() want first intent '%s', got '%s'", tt.wantTag, msg.Intents[0].Tag)
+			}
+		})
+	}
+}
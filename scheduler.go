@@ -0,0 +1,271 @@
+package snowman
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/spy16/snowman/pkg/cron"
+)
+
+// Job represents a message scheduled to be generated by the bot at a specific
+// time (At) or periodically (Cron). When Inject is true, the message is passed
+// through the Handler as if the user 'To' sent it (e.g., to trigger an intent).
+// Otherwise, Body is said to the user directly. Origin identifies the UI the
+// user belongs to when using MultiUI. Channel and Thread are set on injected
+// messages so that they belong to the same dialogue as the messages of the
+// user in that channel or thread (see DialogueKey).
+type Job struct {
+	ID      string    `json:"id"`
+	Cron    string    `json:"cron,omitempty"`
+	At      time.Time `json:"at,omitempty"`
+	To      User      `json:"to"`
+	Origin  string    `json:"origin,omitempty"`
+	Channel string    `json:"channel,omitempty"`
+	Thread  string    `json:"thread,omitempty"`
+	Body    string    `json:"body"`
+	Inject  bool      `json:"inject,omitempty"`
+}
+
+// JobStore is responsible for persisting the scheduled jobs so that they
+// survive restarts of the bot.
+type JobStore interface {
+	// Put creates or replaces the job.
+	Put(job Job) error
+
+	// Delete removes the job. Deleting a job that does not exist is not an
+	// error.
+	Delete(id string) error
+
+	// List returns all the jobs in the store.
+	List() ([]Job, error)
+}
+
+// Schedule validates and persists the job and schedules it. If the job has
+// no ID, a random one is assigned. Schedule can be called before or while the
+// bot is running.
+func (bot *Bot) Schedule(job Job) (Job, error) {
+	if job.ID == "" {
+		job.ID = newJobID()
+	}
+
+	if job.To.ID == "" {
+		return job, errors.New("job target user must be set")
+	} else if (job.Cron == "") == job.At.IsZero() {
+		return job, errors.New("exactly one of job cron or at must be set")
+	}
+
+	if job.Cron != "" {
+		if _, err := cron.Parse(job.Cron); err != nil {
+			return job, err
+		}
+	}
+
+	sched := bot.scheduler()
+	if err := sched.store.Put(job); err != nil {
+		return job, err
+	}
+	sched.add(job)
+	return job, nil
+}
+
+// Unschedule cancels and removes the job.
+func (bot *Bot) Unschedule(id string) error {
+	sched := bot.scheduler()
+	if err := sched.store.Delete(id); err != nil {
+		return err
+	}
+	sched.remove(id)
+	return nil
+}
+
+// Remind schedules body to be said to the user of the dialogue after the
+// given duration. The Origin, Channel and Thread of the job are those of the
+// last message in the dialogue.
+func (bot *Bot) Remind(di Dialogue, after time.Duration, body string) (Job, error) {
+	job := Job{
+		At:   time.Now().Add(after),
+		To:   di.With(),
+		Body: body,
+	}
+	if dc, ok := di.(*dialogueCtx); ok {
+		state := dc.snapshot()
+		job.Origin, job.Channel, job.Thread = state.Origin, state.Channel, state.Thread
+	}
+	return bot.Schedule(job)
+}
+
+func (bot *Bot) scheduler() *scheduler {
+	bot.schedOnce.Do(func() {
+		if bot.Jobs == nil {
+			bot.Jobs = &MemoryJobStore{}
+		}
+		bot.sched = &scheduler{
+			store: bot.Jobs,
+			jobs:  map[string]*scheduledJob{},
+			wake:  make(chan struct{}, 1),
+		}
+	})
+	return bot.sched
+}
+
+// runJobs runs the scheduled jobs until the context is cancelled.
+func (bot *Bot) runJobs(ctx context.Context) {
+	sched := bot.scheduler()
+	if err := sched.load(); err != nil {
+		bot.Logger.Errorf("failed to load scheduled jobs: %v", err)
+	}
+
+	for {
+		due, wait := sched.due(time.Now())
+		for _, job := range due {
+			bot.fire(ctx, job)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+
+		case <-sched.wake:
+			timer.Stop()
+
+		case <-timer.C:
+		}
+	}
+}
+
+func (bot *Bot) fire(ctx context.Context, job Job) {
+	if job.Cron == "" {
+		if err := bot.Jobs.Delete(job.ID); err != nil {
+			bot.Logger.Warnf("failed to delete completed job '%s': %v", job.ID, err)
+		}
+	}
+
+	if job.Inject {
+		bot.receive(ctx, Msg{
+			At:      time.Now(),
+			To:      bot.Self,
+			From:    job.To,
+			Body:    job.Body,
+			Origin:  job.Origin,
+			Channel: job.Channel,
+			Thread:  job.Thread,
+		})
+		return
+	}
+
+	if err := bot.UI.Say(ctx, Msg{
+		At:     time.Now(),
+		To:     job.To,
+		From:   bot.Self,
+		Body:   job.Body,
+		Origin: job.Origin,
+	}); err != nil {
+		bot.Logger.Errorf("failed to deliver scheduled message '%s' to '%s': %v", job.ID, job.To, err)
+	}
+}
+
+// maxJobWait is the maximum time the scheduler sleeps for before checking
+// for due jobs again.
+const maxJobWait = time.Minute
+
+type scheduler struct {
+	store JobStore
+	wake  chan struct{}
+
+	mu   sync.Mutex
+	jobs map[string]*scheduledJob
+}
+
+type scheduledJob struct {
+	job  Job
+	cron *cron.Schedule
+	next time.Time
+}
+
+func (s *scheduler) load() error {
+	jobs, err := s.store.List()
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		s.add(job)
+	}
+	return nil
+}
+
+func (s *scheduler) add(job Job) {
+	sj := &scheduledJob{job: job, next: job.At}
+	if job.Cron != "" {
+		sched, err := cron.Parse(job.Cron)
+		if err != nil {
+			return
+		}
+		sj.cron = sched
+		sj.next = sched.Next(time.Now())
+		if sj.next.IsZero() {
+			return
+		}
+	}
+
+	s.mu.Lock()
+	s.jobs[job.ID] = sj
+	s.mu.Unlock()
+	s.notify()
+}
+
+func (s *scheduler) remove(id string) {
+	s.mu.Lock()
+	delete(s.jobs, id)
+	s.mu.Unlock()
+	s.notify()
+}
+
+// due returns the jobs that are due at the given time and the duration until
+// the next job becomes due. Recurring jobs are rescheduled, others removed.
+func (s *scheduler) due(now time.Time) ([]Job, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []Job
+	wait := maxJobWait
+	for id, sj := range s.jobs {
+		if !sj.next.After(now) {
+			due = append(due, sj.job)
+			if sj.cron == nil {
+				delete(s.jobs, id)
+				continue
+			}
+			sj.next = sj.cron.Next(now)
+		}
+
+		if sj.next.IsZero() {
+			delete(s.jobs, id)
+		} else if d := sj.next.Sub(now); d < wait {
+			wait = d
+		}
+	}
+	return due, wait
+}
+
+func (s *scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func newJobID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,203 @@
+package snowman_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spy16/snowman"
+)
+
+func TestBot_Schedule(t *testing.T) {
+	t.Parallel()
+
+	alice := snowman.User{ID: "alice"}
+	handler := snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+		return di.Say(msg.Context(), "handled "+msg.Body)
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		bot := &snowman.Bot{}
+		table := []struct {
+			title string
+			job   snowman.Job
+		}{
+			{title: "NoTarget", job: snowman.Job{At: time.Now()}},
+			{title: "NoTime", job: snowman.Job{To: alice}},
+			{title: "CronAndAt", job: snowman.Job{To: alice, At: time.Now(), Cron: "* * * * *"}},
+			{title: "InvalidCron", job: snowman.Job{To: alice, Cron: "every day"}},
+		}
+
+		for _, tt := range table {
+			t.Run(tt.title, func(t *testing.T) {
+				if _, err := bot.Schedule(tt.job); err == nil {
+					t.Errorf("Schedule() want error for invalid job")
+				}
+			})
+		}
+
+		job, err := bot.Schedule(snowman.Job{To: alice, Cron: "0 9 * * *"})
+		if err != nil || job.ID == "" {
+			t.Errorf("Schedule() want job with an ID assigned, got %+v (err=%v)", job, err)
+		}
+	})
+
+	t.Run("Say", func(t *testing.T) {
+		ui := newTestUI()
+		jobs := &snowman.MemoryJobStore{}
+		bot := &snowman.Bot{UI: ui, Handler: handler, Jobs: jobs}
+		startBot(t, bot)
+
+		if _, err := bot.Schedule(snowman.Job{To: alice, At: time.Now().Add(20 * time.Millisecond), Body: "wake up"}); err != nil {
+			t.Fatalf("Schedule() unexpected error: %v", err)
+		}
+
+		// without Inject, the body is said directly without the handler.
+		expectSaid(t, ui, "alice", "wake up")
+		expectNoJobs(t, jobs)
+	})
+
+	t.Run("Inject", func(t *testing.T) {
+		ui := newTestUI()
+		bot := &snowman.Bot{UI: ui, Handler: handler}
+		startBot(t, bot)
+
+		if _, err := bot.Schedule(snowman.Job{To: alice, At: time.Now(), Body: "report", Inject: true}); err != nil {
+			t.Fatalf("Schedule() unexpected error: %v", err)
+		}
+		expectSaid(t, ui, "alice", "handled report")
+	})
+
+	t.Run("Unschedule", func(t *testing.T) {
+		ui := newTestUI()
+		jobs := &snowman.MemoryJobStore{}
+		bot := &snowman.Bot{UI: ui, Handler: handler, Jobs: jobs}
+		startBot(t, bot)
+
+		job, err := bot.Schedule(snowman.Job{To: alice, At: time.Now().Add(50 * time.Millisecond), Body: "never"})
+		if err != nil {
+			t.Fatalf("Schedule() unexpected error: %v", err)
+		}
+		if err := bot.Unschedule(job.ID); err != nil {
+			t.Fatalf("Unschedule() unexpected error: %v", err)
+		}
+		expectNoJobs(t, jobs)
+
+		select {
+		case msg := <-ui.out:
+			t.Errorf("want nothing said after Unschedule, got '%s'", msg.Body)
+		case <-time.After(150 * time.Millisecond):
+		}
+	})
+
+	t.Run("Remind", func(t *testing.T) {
+		ui := newTestUI()
+		jobs := make(chan snowman.Job, 1)
+		bot := &snowman.Bot{UI: ui}
+		bot.Handler = snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+			job, err := bot.Remind(di, 20*time.Millisecond, "time to "+msg.Body)
+			if err != nil {
+				return err
+			}
+			jobs <- job
+			return di.Say(msg.Context(), "will remind you")
+		})
+		startBot(t, bot)
+
+		msg := userMsg("alice", "stretch")
+		msg.Channel, msg.Thread = "c1", "t1"
+		ui.in <- msg
+		expectSaid(t, ui, "alice", "will remind you")
+		expectSaid(t, ui, "alice", "time to stretch")
+
+		if job := <-jobs; job.Channel != "c1" || job.Thread != "t1" {
+			t.Errorf("Remind() want job in channel 'c1' and thread 't1', got %+v", job)
+		}
+	})
+
+	t.Run("InjectInChannel", func(t *testing.T) {
+		ui := newTestUI()
+		bot := &snowman.Bot{
+			UI:          ui,
+			DialogueKey: snowman.PerChannel,
+			Handler: snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+				n, _ := snowman.GetInt(di, "count")
+				di.Set("count", n+1)
+				return di.Say(msg.Context(), fmt.Sprintf("%s #%d", msg.Body, n+1))
+			}),
+		}
+		startBot(t, bot)
+
+		msg := userMsg("alice", "hi")
+		msg.Channel = "c1"
+		ui.in <- msg
+		expectSaid(t, ui, "alice", "hi #1")
+
+		// the injected message must continue the dialogue of the channel.
+		job := snowman.Job{To: alice, Channel: "c1", At: time.Now(), Body: "report", Inject: true}
+		if _, err := bot.Schedule(job); err != nil {
+			t.Fatalf("Schedule() unexpected error: %v", err)
+		}
+		expectSaid(t, ui, "alice", "report #2")
+	})
+
+	t.Run("PastDueAfterRestart", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "jobs.json")
+
+		// jobs scheduled by an earlier run that became due while the bot was
+		// down must fire as soon as it starts again.
+		earlier := &snowman.FileJobStore{Path: path}
+		for _, job := range []snowman.Job{
+			{ID: "say", To: alice, At: time.Now().Add(-time.Hour), Body: "missed"},
+			{ID: "inject", To: alice, At: time.Now().Add(-time.Hour), Body: "overdue", Inject: true},
+		} {
+			if err := earlier.Put(job); err != nil {
+				t.Fatalf("Put() unexpected error: %v", err)
+			}
+		}
+
+		ui := newTestUI()
+		jobs := &snowman.FileJobStore{Path: path}
+		startBot(t, &snowman.Bot{UI: ui, Handler: handler, Jobs: jobs})
+
+		got := map[string]bool{}
+		for i := 0; i < 2; i++ {
+			select {
+			case msg := <-ui.out:
+				got[msg.Body] = true
+			case <-time.After(2 * time.Second):
+				t.Fatalf("past-due jobs not fired, got %v", got)
+			}
+		}
+		if !got["missed"] || !got["handled overdue"] {
+			t.Errorf("want 'missed' said and 'overdue' handled, got %v", got)
+		}
+		expectNoJobs(t, jobs)
+	})
+}
+
+func expectSaid(t *testing.T, ui *testUI, to, body string) {
+	t.Helper()
+
+	select {
+	case msg := <-ui.out:
+		if msg.To.ID != to || msg.Body != body {
+			t.Errorf("want '%s' said to '%s', got '%s' to '%s'", body, to, msg.Body, msg.To.ID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("nothing said, want '%s'", body)
+	}
+}
+
+func expectNoJobs(t *testing.T, store snowman.JobStore) {
+	t.Helper()
+
+	jobs, err := store.List()
+	if err != nil {
+		t.Fatalf("List() unexpected error: %v", err)
+	}
+	if len(jobs) != 0 {
+		t.Errorf("want no jobs left in the store, got %+v", jobs)
+	}
+}
@@ -0,0 +1,207 @@
+// Package slots provides a snowman.Handler that fills the slots required by an
+// intent by asking the user for the missing ones before invoking the handler
+// for the intent.
+package slots
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spy16/snowman"
+)
+
+var _ snowman.Handler = (*Filler)(nil)
+
+// Dialogue state keys used to track the slot filling progress.
+const (
+	keyIntent   = "slots.intent"
+	keyResponse = "slots.response"
+	keyAsking   = "slots.asking"
+)
+
+// Slot represents a piece of information required by an intent. Prompt is
+// said to ask for the value and Reprompt is said when the value provided by
+// the user fails the Type validation.
+type Slot struct {
+	Name     string
+	Prompt   string
+	Reprompt string
+	Type     Type
+}
+
+// New returns a new slot Filler. Messages that are not related to any of the
+// intents registered with the filler are passed on to next. If next is nil,
+// snowman.NotUnderstood() is used.
+func New(next snowman.Handler) *Filler {
+	if next == nil {
+		next = snowman.NotUnderstood()
+	}
+	return &Filler{
+		next:    next,
+		intents: map[string]*requirement{},
+	}
+}
+
+// Filler implements snowman.Handler and fills the required slots of intents.
+// Values available in the intent context (e.g., from named captures of regex
+// patterns) are used first, the user is asked for the rest one at a time.
+// Once all the slots are filled, the handler for the intent is invoked with
+// msg.Intents[0] set to the intent with the slot values in its context.
+type Filler struct {
+	// Cancel lists the intent tags that abort the slot filling.
+	Cancel []string
+
+	// Cancelled is said when the slot filling is aborted.
+	Cancelled string
+
+	next    snowman.Handler
+	intents map[string]*requirement
+}
+
+type requirement struct {
+	handler snowman.Handler
+	slots   []Slot
+}
+
+// Require registers the handler for the intent tag along with the slots that
+// must be filled before the handler is invoked. Slots are asked for in the
+// order given. Require panics if a slot has no name.
+func (f *Filler) Require(tag string, h snowman.Handler, slots ...Slot) {
+	slots = append([]Slot(nil), slots...)
+	for i, slot := range slots {
+		if slot.Name == "" {
+			panic(fmt.Sprintf("slots: slot %d of '%s' has no name", i, tag))
+		}
+
+		if slot.Type == nil {
+			slots[i].Type = Text
+		}
+	}
+
+	f.intents[tag] = &requirement{
+		handler: h,
+		slots:   slots,
+	}
+}
+
+// Handle starts filling the slots for a registered intent found in the message
+// or continues filling the slots for the intent that is in progress.
+func (f *Filler) Handle(msg *snowman.Msg, di snowman.Dialogue) error {
+	for _, intent := range msg.Intents {
+		if f.isCancel(intent.Tag) && Pending(di) != "" {
+			f.reset(di)
+			if f.Cancelled == "" {
+				return nil
+			}
+			return di.Say(msg.Context(), f.Cancelled)
+		}
+
+		if req, found := f.intents[intent.Tag]; found {
+			return f.start(req, intent, msg, di)
+		}
+	}
+
+	tag := Pending(di)
+	req, found := f.intents[tag]
+	if !found {
+		return f.next.Handle(msg, di)
+	}
+
+	name, _ := snowman.GetString(di, keyAsking)
+	for _, slot := range req.slots {
+		if slot.Name != name {
+			continue
+		}
+
+		val, err := slot.Type(msg.Body)
+		if err != nil {
+			reprompt := slot.Reprompt
+			if reprompt == "" {
+				reprompt = slot.Prompt
+			}
+			return di.Say(msg.Context(), reprompt)
+		}
+		di.SetSlot(slot.Name, val)
+		break
+	}
+
+	return f.askOrInvoke(req, msg, di)
+}
+
+// Pending returns the tag of the intent for which slots are being filled in
+// the dialogue, or empty string if there is none.
+func Pending(di snowman.Dialogue) string {
+	tag, _ := snowman.GetString(di, keyIntent)
+	return tag
+}
+
+func (f *Filler) start(req *requirement, intent snowman.Intent, msg *snowman.Msg, di snowman.Dialogue) error {
+	f.reset(di)
+	di.Set(keyIntent, intent.Tag)
+	di.Set(keyResponse, intent.Response)
+
+	for _, slot := range req.slots {
+		raw, found := intent.Context[slot.Name]
+		if !found {
+			continue
+		}
+
+		if val, err := slot.Type(fmt.Sprint(raw)); err == nil {
+			di.SetSlot(slot.Name, val)
+		}
+	}
+
+	return f.askOrInvoke(req, msg, di)
+}
+
+func (f *Filler) askOrInvoke(req *requirement, msg *snowman.Msg, di snowman.Dialogue) error {
+	for _, slot := range req.slots {
+		if _, filled := di.Slot(slot.Name); !filled {
+			di.Set(keyAsking, slot.Name)
+			return di.Say(msg.Context(), slot.Prompt)
+		}
+	}
+
+	// numbers come back as float64 if the dialogue state was persisted in
+	// between (e.g., to JSON). so they are parsed again to restore the type
+	// of the slot (e.g., int for Int).
+	values := di.Slots()
+	for _, slot := range req.slots {
+		num, isFloat := values[slot.Name].(float64)
+		if !isFloat {
+			continue
+		}
+
+		if val, err := slot.Type(strconv.FormatFloat(num, 'f', -1, 64)); err == nil {
+			values[slot.Name] = val
+		}
+	}
+
+	response, _ := snowman.GetString(di, keyResponse)
+	intent := snowman.Intent{
+		Tag:        Pending(di),
+		Context:    values,
+		Response:   response,
+		Confidence: 1,
+	}
+	msg.Intents = append([]snowman.Intent{intent}, msg.Intents...)
+
+	f.reset(di)
+	return req.handler.Handle(msg, di)
+}
+
+func (f *Filler) isCancel(tag string) bool {
+	for _, t := range f.Cancel {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Filler) reset(di snowman.Dialogue) {
+	di.Delete(keyIntent)
+	di.Delete(keyResponse)
+	di.Delete(keyAsking)
+	di.ClearSlots()
+}
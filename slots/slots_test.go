@@ -0,0 +1,171 @@
+package slots_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spy16/snowman"
+	"github.com/spy16/snowman/slots"
+)
+
+func TestFiller(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		title  string
+		inputs []string
+		want   []string
+	}{
+		{
+			title:  "Prefilled",
+			inputs: []string{"book city=Paris guests=2"},
+			want:   []string{"booked Paris for 2 (int)"},
+		},
+		{
+			title:  "Ask",
+			inputs: []string{"book", "Paris", "3"},
+			want:   []string{"which city?", "how many guests?", "booked Paris for 3 (int)"},
+		},
+		{
+			title:  "PartiallyPrefilled",
+			inputs: []string{"book city=Rome", "4"},
+			want:   []string{"how many guests?", "booked Rome for 4 (int)"},
+		},
+		{
+			title:  "InvalidPrefill",
+			inputs: []string{"book guests=lots", "Rome", "4"},
+			want:   []string{"which city?", "how many guests?", "booked Rome for 4 (int)"},
+		},
+		{
+			title:  "Reprompt",
+			inputs: []string{"book city=Paris", "many", "2"},
+			want:   []string{"how many guests?", "a number please", "booked Paris for 2 (int)"},
+		},
+		{
+			title:  "Cancel",
+			inputs: []string{"book", "stop", "Paris"},
+			want:   []string{"which city?", "cancelled", "not understood"},
+		},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.title, func(t *testing.T) {
+			ui := &scriptedUI{inputs: tt.inputs}
+			bot := snowman.Bot{UI: ui, Handler: newFiller(), Logger: snowman.NoOpLogger{}}
+			bot.Use(tagByBody)
+
+			if err := bot.Run(context.Background()); err != nil {
+				t.Fatalf("Run() unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(ui.said, tt.want) {
+				t.Errorf("Run() want replies %q, got %q", tt.want, ui.said)
+			}
+		})
+	}
+}
+
+func TestFiller_Persisted(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "dialogues.jsonl")
+	run := func(inputs ...string) []string {
+		t.Helper()
+
+		store, err := snowman.OpenFileStore(path)
+		if err != nil {
+			t.Fatalf("OpenFileStore() unexpected error: %v", err)
+		}
+		defer store.Close()
+
+		ui := &scriptedUI{inputs: inputs}
+		bot := snowman.Bot{UI: ui, Handler: newFiller(), Store: store, Logger: snowman.NoOpLogger{}}
+		bot.Use(tagByBody)
+		if err := bot.Run(context.Background()); err != nil {
+			t.Fatalf("Run() unexpected error: %v", err)
+		}
+		return ui.said
+	}
+
+	// the guests slot is filled before the restart and must still be an int
+	// when the handler is invoked after it.
+	for _, guests := range []string{"2", "1500000"} {
+		if got := run("party", guests); !reflect.DeepEqual(got, []string{"how many guests?", "which city?"}) {
+			t.Fatalf("Run() want guests and city asked, got %q", got)
+		}
+
+		want := []string{"booked Paris for " + guests + " (int)"}
+		if got := run("Paris"); !reflect.DeepEqual(got, want) {
+			t.Errorf("Run() want %q after restart, got %q", want, got)
+		}
+	}
+}
+
+func newFiller() *slots.Filler {
+	booked := snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+		intent := msg.Intents[0]
+		if intent.Tag != "book" && intent.Tag != "party" {
+			return fmt.Errorf("unexpected intent '%s'", intent.Tag)
+		}
+
+		guests := intent.Context["guests"]
+		return di.Say(msg.Context(), fmt.Sprintf("booked %v for %v (%T)", intent.Context["city"], guests, guests))
+	})
+
+	city := slots.Slot{Name: "city", Prompt: "which city?"}
+	guests := slots.Slot{Name: "guests", Prompt: "how many guests?", Reprompt: "a number please", Type: slots.Int}
+
+	f := slots.New(snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+		return di.Say(msg.Context(), "not understood")
+	}))
+	f.Cancel = []string{"stop"}
+	f.Cancelled = "cancelled"
+	f.Require("book", booked, city, guests)
+	f.Require("party", booked, guests, city)
+	return f
+}
+
+// tagByBody tags messages with an intent named after the first word of the
+// body. Words of the form 'name=value' that follow are added to the context
+// of the intent.
+func tagByBody(next snowman.Handler) snowman.Handler {
+	return snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+		words := strings.Fields(msg.Body)
+		if len(words) == 0 {
+			return next.Handle(msg, di)
+		}
+
+		intent := snowman.Intent{Tag: words[0], Confidence: 1}
+		for _, word := range words[1:] {
+			if i := strings.IndexByte(word, '='); i > 0 {
+				if intent.Context == nil {
+					intent.Context = map[string]interface{}{}
+				}
+				intent.Context[word[:i]] = word[i+1:]
+			}
+		}
+		msg.Intents = append(msg.Intents, intent)
+		return next.Handle(msg, di)
+	})
+}
+
+type scriptedUI struct {
+	inputs []string
+	said   []string
+}
+
+func (ui *scriptedUI) Say(_ context.Context, msg snowman.Msg) error {
+	ui.said = append(ui.said, msg.Body)
+	return nil
+}
+
+func (ui *scriptedUI) Listen(_ context.Context, receive func(msg snowman.Msg)) error {
+	for _, input := range ui.inputs {
+		receive(snowman.Msg{From: snowman.User{ID: "user"}, Body: input})
+	}
+	return nil
+}
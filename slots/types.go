@@ -0,0 +1,83 @@
+package slots
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Type parses and validates the text provided by the user for a slot. The
+// returned value is stored in the dialogue slots and must be serialisable.
+type Type func(text string) (interface{}, error)
+
+// Text accepts any non-empty text.
+func Text(text string) (interface{}, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, fmt.Errorf("empty text")
+	}
+	return text, nil
+}
+
+// Int accepts integer values.
+func Int(text string) (interface{}, error) {
+	return strconv.Atoi(strings.TrimSpace(text))
+}
+
+// Number accepts integer or decimal values.
+func Number(text string) (interface{}, error) {
+	return strconv.ParseFloat(strings.TrimSpace(text), 64)
+}
+
+// Bool accepts yes/no style answers.
+func Bool(text string) (interface{}, error) {
+	switch strings.ToLower(strings.TrimSpace(text)) {
+	case "yes", "y", "yeah", "sure", "true", "ok", "okay":
+		return true, nil
+
+	case "no", "n", "nah", "nope", "false":
+		return false, nil
+
+	default:
+		return nil, fmt.Errorf("'%s' is not a yes/no answer", text)
+	}
+}
+
+// Date accepts dates in YYYY-MM-DD format and the words 'today', 'tomorrow'
+// and 'yesterday'. The date is stored in YYYY-MM-DD format.
+func Date(text string) (interface{}, error) {
+	const layout = "2006-01-02"
+
+	now := time.Now()
+	switch strings.ToLower(strings.TrimSpace(text)) {
+	case "today":
+		return now.Format(layout), nil
+
+	case "tomorrow":
+		return now.AddDate(0, 0, 1).Format(layout), nil
+
+	case "yesterday":
+		return now.AddDate(0, 0, -1).Format(layout), nil
+	}
+
+	t, err := time.Parse(layout, strings.TrimSpace(text))
+	if err != nil {
+		return nil, fmt.Errorf("'%s' is not a valid date", text)
+	}
+	return t.Format(layout), nil
+}
+
+// OneOf returns a Type that accepts only one of the given choices. Matching
+// is case-insensitive and the choice is stored as given here.
+func OneOf(choices ...string) Type {
+	return func(text string) (interface{}, error) {
+		text = strings.TrimSpace(text)
+		for _, choice := range choices {
+			if strings.EqualFold(choice, text) {
+				return choice, nil
+			}
+		}
+		return nil, fmt.Errorf("'%s' is not one of %v", text, choices)
+	}
+}
@@ -0,0 +1,104 @@
+package snowman
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// GetString returns the dialogue state value for the key as a string. Returns
+// false if the key is not set or the value is not a string.
+func GetString(di Dialogue, key string) (string, bool) {
+	val, found := di.Get(key)
+	if !found {
+		return "", false
+	}
+	s, ok := val.(string)
+	return s, ok
+}
+
+// GetInt returns the dialogue state value for the key as an int. Numeric
+// values of other types (e.g., float64 values restored from JSON) and numeric
+// strings are converted.
+func GetInt(di Dialogue, key string) (int, bool) {
+	val, found := di.Get(key)
+	if !found {
+		return 0, false
+	}
+	f, ok := toFloat(val)
+	return int(f), ok
+}
+
+// GetFloat returns the dialogue state value for the key as a float64. Numeric
+// values of other types and numeric strings are converted.
+func GetFloat(di Dialogue, key string) (float64, bool) {
+	val, found := di.Get(key)
+	if !found {
+		return 0, false
+	}
+	return toFloat(val)
+}
+
+// GetBool returns the dialogue state value for the key as a bool.
+func GetBool(di Dialogue, key string) (bool, bool) {
+	val, found := di.Get(key)
+	if !found {
+		return false, false
+	}
+
+	switch v := val.(type) {
+	case bool:
+		return v, true
+
+	case string:
+		b, err := strconv.ParseBool(v)
+		return b, err == nil
+
+	default:
+		return false, false
+	}
+}
+
+// GetTime returns the dialogue state value for the key as time.Time. RFC3339
+// formatted strings are parsed.
+func GetTime(di Dialogue, key string) (time.Time, bool) {
+	val, found := di.Get(key)
+	if !found {
+		return time.Time{}, false
+	}
+
+	switch v := val.(type) {
+	case time.Time:
+		return v, true
+
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		return t, err == nil
+
+	default:
+		return time.Time{}, false
+	}
+}
+
+func toFloat(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,91 @@
+package snowman_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/spy16/snowman"
+)
+
+func TestGetters(t *testing.T) {
+	t.Parallel()
+
+	at := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	// values as they come back from a JSON round-trip (e.g., FileStore).
+	var restored map[string]interface{}
+	data, _ := json.Marshal(map[string]interface{}{"count": 3, "at": at, "ok": true})
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+
+	di := &valuesDialogue{values: map[string]interface{}{
+		"int":      3,
+		"json_int": restored["count"],
+		"str_int":  "42",
+		"float":    2.5,
+		"str":      "alice",
+		"bool":     true,
+		"json_ok":  restored["ok"],
+		"str_bool": "true",
+		"time":     at,
+		"json_at":  restored["at"],
+		"garbage":  "not a value",
+	}}
+
+	table := []struct {
+		title string
+		get   func() (interface{}, bool)
+		want  interface{}
+		ok    bool
+	}{
+		{title: "Int", get: func() (interface{}, bool) { return snowman.GetInt(di, "int") }, want: 3, ok: true},
+		{title: "Int/JSON", get: func() (interface{}, bool) { return snowman.GetInt(di, "json_int") }, want: 3, ok: true},
+		{title: "Int/String", get: func() (interface{}, bool) { return snowman.GetInt(di, "str_int") }, want: 42, ok: true},
+		{title: "Int/Invalid", get: func() (interface{}, bool) { return snowman.GetInt(di, "garbage") }, want: 0},
+		{title: "Int/Missing", get: func() (interface{}, bool) { return snowman.GetInt(di, "missing") }, want: 0},
+		{title: "Float", get: func() (interface{}, bool) { return snowman.GetFloat(di, "float") }, want: 2.5, ok: true},
+		{title: "Float/String", get: func() (interface{}, bool) { return snowman.GetFloat(di, "str_int") }, want: 42.0, ok: true},
+		{title: "String", get: func() (interface{}, bool) { return snowman.GetString(di, "str") }, want: "alice", ok: true},
+		{title: "String/NotString", get: func() (interface{}, bool) { return snowman.GetString(di, "int") }, want: ""},
+		{title: "Bool", get: func() (interface{}, bool) { return snowman.GetBool(di, "bool") }, want: true, ok: true},
+		{title: "Bool/JSON", get: func() (interface{}, bool) { return snowman.GetBool(di, "json_ok") }, want: true, ok: true},
+		{title: "Bool/String", get: func() (interface{}, bool) { return snowman.GetBool(di, "str_bool") }, want: true, ok: true},
+		{title: "Bool/Invalid", get: func() (interface{}, bool) { return snowman.GetBool(di, "garbage") }, want: false},
+		{title: "Bool/NotBool", get: func() (interface{}, bool) { return snowman.GetBool(di, "int") }, want: false},
+		{title: "Time", get: func() (interface{}, bool) { return snowman.GetTime(di, "time") }, want: at, ok: true},
+		{title: "Time/JSON", get: func() (interface{}, bool) { return snowman.GetTime(di, "json_at") }, want: at, ok: true},
+		{title: "Time/Invalid", get: func() (interface{}, bool) { return snowman.GetTime(di, "garbage") }, want: time.Time{}},
+		{title: "Time/Missing", get: func() (interface{}, bool) { return snowman.GetTime(di, "missing") }, want: time.Time{}},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.title, func(t *testing.T) {
+			got, ok := tt.get()
+			if ok != tt.ok {
+				t.Errorf("want ok=%t, got ok=%t", tt.ok, ok)
+			}
+
+			if tm, isTime := got.(time.Time); isTime {
+				if !tm.Equal(tt.want.(time.Time)) {
+					t.Errorf("want %v, got %v", tt.want, got)
+				}
+			} else if got != tt.want {
+				t.Errorf("want %#v, got %#v", tt.want, got)
+			}
+		})
+	}
+}
+
+// valuesDialogue is a Dialogue with only the state values.
+type valuesDialogue struct {
+	snowman.Dialogue
+
+	values map[string]interface{}
+}
+
+func (vd *valuesDialogue) Get(key string) (interface{}, bool) {
+	val, found := vd.values[key]
+	return val, found
+}
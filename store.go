@@ -0,0 +1,63 @@
+package snowman
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned by stores when the requested entry does not exist.
+var ErrNotFound = errors.New("not found")
+
+// DialogueStore is responsible for persisting the state of dialogues between
+// messages (and possibly between restarts of the bot).
+type DialogueStore interface {
+	// Get returns the state of the dialogue with given ID. Returns ErrNotFound
+	// if the dialogue does not exist in the store.
+	Get(id string) (*DialogueState, error)
+
+	// Put creates or replaces the dialogue state.
+	Put(state DialogueState) error
+
+	// Delete removes the dialogue state from the store. Deleting a dialogue
+	// that does not exist is not an error.
+	Delete(id string) error
+
+	// Range invokes fn for every dialogue state in the store until fn returns
+	// false. fn must be free to call other methods of the store.
+	Range(fn func(state DialogueState) bool) error
+}
+
+// Flusher can be implemented by the stores that buffer writes. Bot flushes
+// the stores implementing Flusher when shutting down.
+type Flusher interface {
+	Flush() error
+}
+
+// DialogueState represents the persistable state of a dialogue. Origin,
+// Channel and Thread are those of the last message received in the dialogue.
+type DialogueState struct {
+	ID         string                 `json:"id"`
+	With       User                   `json:"with"`
+	Origin     string                 `json:"origin,omitempty"`
+	Channel    string                 `json:"channel,omitempty"`
+	Thread     string                 `json:"thread,omitempty"`
+	Values     map[string]interface{} `json:"values,omitempty"`
+	Slots      map[string]interface{} `json:"slots,omitempty"`
+	LastActive time.Time              `json:"last_active"`
+}
+
+// Clone returns a copy of the state that does not share the value and slot
+// maps with the original.
+func (ds DialogueState) Clone() DialogueState {
+	cloned := ds
+	cloned.Values = cloneMap(ds.Values)
+	cloned.Slots = cloneMap(ds.Slots)
+	return cloned
+}
+
+func cloneMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	return cloneMerge(m)
+}
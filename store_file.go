@@ -0,0 +1,279 @@
+package snowman
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var (
+	_ DialogueStore = (*FileStore)(nil)
+	_ Flusher       = (*FileStore)(nil)
+)
+
+// minCompactRecords is the minimum number of records in the file before the
+// FileStore considers compacting it.
+const minCompactRecords = 1000
+
+// OpenFileStore opens (or creates) a FileStore backed by the file at the given
+// path. Existing file is loaded and compacted.
+func OpenFileStore(path string) (*FileStore, error) {
+	fs := &FileStore{path: path}
+	if err := fs.load(); err != nil {
+		return nil, err
+	}
+
+	if err := fs.compact(); err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+// FileStore implements a durable DialogueStore backed by a local JSON-lines
+// file. All the dialogue states are kept in memory and every Put/Delete is
+// appended to the file as a record. The file is compacted when opened and
+// whenever the number of stale records grows beyond the number of live ones.
+// FileStore must be closed after use.
+type FileStore struct {
+	// Logger is used to report the failures of the compactions done while
+	// storing the dialogues. Defaults to NoOpLogger.
+	Logger Logger
+
+	path string
+
+	mu      sync.RWMutex
+	file    *os.File
+	states  map[string]DialogueState
+	records int
+
+	// retryAt is the number of records after which compaction is attempted
+	// again once it has failed.
+	retryAt int
+}
+
+type fileRecord struct {
+	Op    string         `json:"op"`
+	ID    string         `json:"id"`
+	State *DialogueState `json:"state,omitempty"`
+}
+
+// Get returns a copy of the dialogue state stored against the id.
+func (fs *FileStore) Get(id string) (*DialogueState, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	state, found := fs.states[id]
+	if !found {
+		return nil, ErrNotFound
+	}
+	state = state.Clone()
+	return &state, nil
+}
+
+// Put stores the dialogue state and appends it to the file.
+func (fs *FileStore) Put(state DialogueState) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	state = state.Clone()
+	if err := fs.append(fileRecord{Op: "put", ID: state.ID, State: &state}); err != nil {
+		return err
+	}
+	fs.states[state.ID] = state
+	fs.maybeCompact()
+	return nil
+}
+
+// Delete removes the dialogue state and appends a delete record to the file.
+func (fs *FileStore) Delete(id string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if _, found := fs.states[id]; !found {
+		return nil
+	}
+
+	if err := fs.append(fileRecord{Op: "del", ID: id}); err != nil {
+		return err
+	}
+	delete(fs.states, id)
+	fs.maybeCompact()
+	return nil
+}
+
+// Range invokes fn with a copy of every dialogue state in the store.
+func (fs *FileStore) Range(fn func(state DialogueState) bool) error {
+	fs.mu.RLock()
+	states := make([]DialogueState, 0, len(fs.states))
+	for _, state := range fs.states {
+		states = append(states, state.Clone())
+	}
+	fs.mu.RUnlock()
+
+	for _, state := range states {
+		if !fn(state) {
+			break
+		}
+	}
+	return nil
+}
+
+// Flush commits the records written so far to the disk.
+func (fs *FileStore) Flush() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.file == nil {
+		return os.ErrClosed
+	}
+	return fs.file.Sync()
+}
+
+// Close flushes the file to the disk and closes it.
+func (fs *FileStore) Close() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.file == nil {
+		return nil
+	}
+
+	if err := fs.file.Sync(); err != nil {
+		_ = fs.file.Close()
+		return err
+	}
+	err := fs.file.Close()
+	fs.file = nil
+	return err
+}
+
+func (fs *FileStore) append(rec fileRecord) error {
+	if fs.file == nil {
+		return os.ErrClosed
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fs.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	fs.records++
+	return nil
+}
+
+// maybeCompact compacts the file if it has grown enough. Failures are only
+// logged since the record that triggered the compaction has already been
+// stored.
+func (fs *FileStore) maybeCompact() {
+	if fs.records < minCompactRecords || fs.records < 2*len(fs.states) || fs.records < fs.retryAt {
+		return
+	}
+
+	records := fs.records
+	err := fs.file.Close()
+	fs.file = nil
+	if err == nil {
+		if err = fs.compact(); err == nil {
+			fs.retryAt = 0
+			return
+		}
+	}
+
+	// the existing file is intact. so keep appending to it to remain usable.
+	f, openErr := os.OpenFile(fs.path, os.O_WRONLY|os.O_APPEND, 0)
+	if openErr != nil {
+		fs.logger().Errorf("failed to compact '%s': %v (reopen failed: %v)", fs.path, err, openErr)
+		return
+	}
+	fs.file, fs.records, fs.retryAt = f, records, records+minCompactRecords
+	fs.logger().Warnf("failed to compact '%s', retrying after %d records: %v", fs.path, minCompactRecords, err)
+}
+
+func (fs *FileStore) logger() Logger {
+	if fs.Logger == nil {
+		return NoOpLogger{}
+	}
+	return fs.Logger
+}
+
+func (fs *FileStore) load() error {
+	fs.states = map[string]DialogueState{}
+
+	f, err := os.Open(fs.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	rd := bufio.NewReader(f)
+	for line := 1; ; line++ {
+		data, err := rd.ReadBytes('\n')
+		if err == io.EOF && len(data) == 0 {
+			return nil
+		} else if err != nil && err != io.EOF {
+			return err
+		}
+
+		var rec fileRecord
+		if jsonErr := json.Unmarshal(data, &rec); jsonErr != nil {
+			if err == io.EOF {
+				// a partially written last record from a crash. ignore it.
+				return nil
+			}
+			return fmt.Errorf("error in '%s' at line %d: %v", fs.path, line, jsonErr)
+		}
+
+		switch rec.Op {
+		case "put":
+			if rec.State != nil {
+				fs.states[rec.ID] = *rec.State
+			}
+
+		case "del":
+			delete(fs.states, rec.ID)
+		}
+	}
+}
+
+// compact writes all the live states into a new file and atomically replaces
+// the existing file with it. The new file is left open for appends.
+func (fs *FileStore) compact() error {
+	tmp, err := os.CreateTemp(filepath.Dir(fs.path), filepath.Base(fs.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	fs.file, fs.records = tmp, 0
+	fail := func(err error) error {
+		_ = tmp.Close()
+		fs.file = nil
+		return err
+	}
+
+	for id := range fs.states {
+		state := fs.states[id]
+		if err := fs.append(fileRecord{Op: "put", ID: id, State: &state}); err != nil {
+			return fail(err)
+		}
+	}
+
+	if err := tmp.Sync(); err != nil {
+		return fail(err)
+	}
+
+	if err := os.Rename(tmp.Name(), fs.path); err != nil {
+		return fail(err)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package snowman
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+var (
+	_ JobStore = (*MemoryJobStore)(nil)
+	_ JobStore = (*FileJobStore)(nil)
+)
+
+// MemoryJobStore implements an in-memory JobStore. Jobs are lost when the
+// process exits. Zero value is ready for use.
+type MemoryJobStore struct {
+	mu   sync.RWMutex
+	jobs map[string]Job
+}
+
+// Put stores the job.
+func (ms *MemoryJobStore) Put(job Job) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if ms.jobs == nil {
+		ms.jobs = map[string]Job{}
+	}
+	ms.jobs[job.ID] = job
+	return nil
+}
+
+// Delete removes the job.
+func (ms *MemoryJobStore) Delete(id string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	delete(ms.jobs, id)
+	return nil
+}
+
+// List returns all the jobs sorted by ID.
+func (ms *MemoryJobStore) List() ([]Job, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	return sortedJobs(ms.jobs), nil
+}
+
+// FileJobStore implements a durable JobStore backed by a JSON file. Entire
+// file is rewritten atomically on every change, so it is meant for modest
+// number of jobs.
+type FileJobStore struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+// Put stores the job and rewrites the file.
+func (fs *FileJobStore) Put(job Job) error {
+	return fs.update(func(jobs map[string]Job) { jobs[job.ID] = job })
+}
+
+// Delete removes the job and rewrites the file.
+func (fs *FileJobStore) Delete(id string) error {
+	return fs.update(func(jobs map[string]Job) { delete(jobs, id) })
+}
+
+// List returns all the jobs in the file sorted by ID.
+func (fs *FileJobStore) List() ([]Job, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	jobs, err := fs.read()
+	if err != nil {
+		return nil, err
+	}
+	return sortedJobs(jobs), nil
+}
+
+func (fs *FileJobStore) update(apply func(jobs map[string]Job)) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	jobs, err := fs.read()
+	if err != nil {
+		return err
+	}
+	apply(jobs)
+
+	data, err := json.MarshalIndent(sortedJobs(jobs), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(fs.Path), filepath.Base(fs.Path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fs.Path)
+}
+
+func (fs *FileJobStore) read() (map[string]Job, error) {
+	jobs := map[string]Job{}
+
+	data, err := os.ReadFile(fs.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return jobs, nil
+		}
+		return nil, err
+	}
+
+	var list []Job
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+
+	for _, job := range list {
+		jobs[job.ID] = job
+	}
+	return jobs, nil
+}
+
+func sortedJobs(jobs map[string]Job) []Job {
+	list := make([]Job, 0, len(jobs))
+	for _, job := range jobs {
+		list = append(list, job)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
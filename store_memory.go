@@ -0,0 +1,138 @@
+package snowman
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+var _ DialogueStore = (*MemoryStore)(nil)
+
+// MemoryStore implements an in-memory DialogueStore. MemoryStore evicts the
+// least recently used dialogues when MaxSize is reached and the dialogues that
+// have not been accessed within TTL. Zero value is ready for use and retains
+// everything forever.
+type MemoryStore struct {
+	MaxSize int
+	TTL     time.Duration
+
+	mu    sync.Mutex
+	lru   *list.List
+	items map[string]*list.Element
+}
+
+type memEntry struct {
+	state    DialogueState
+	lastUsed time.Time
+}
+
+// Get returns a copy of the dialogue state stored against the id.
+func (ms *MemoryStore) Get(id string) (*DialogueState, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.init()
+
+	el, found := ms.items[id]
+	if !found {
+		return nil, ErrNotFound
+	}
+
+	entry := el.Value.(*memEntry)
+	if ms.expired(entry) {
+		ms.remove(el)
+		return nil, ErrNotFound
+	}
+	entry.lastUsed = time.Now()
+	ms.lru.MoveToFront(el)
+
+	state := entry.state.Clone()
+	return &state, nil
+}
+
+// Put stores a copy of the dialogue state and evicts least recently used
+// entries if the store has grown beyond MaxSize.
+func (ms *MemoryStore) Put(state DialogueState) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.init()
+
+	entry := &memEntry{
+		state:    state.Clone(),
+		lastUsed: time.Now(),
+	}
+
+	if el, found := ms.items[state.ID]; found {
+		el.Value = entry
+		ms.lru.MoveToFront(el)
+	} else {
+		ms.items[state.ID] = ms.lru.PushFront(entry)
+	}
+
+	for ms.MaxSize > 0 && ms.lru.Len() > ms.MaxSize {
+		ms.remove(ms.lru.Back())
+	}
+
+	// entries are ordered by last use. so expired ones are all at the back.
+	for el := ms.lru.Back(); el != nil && ms.expired(el.Value.(*memEntry)); el = ms.lru.Back() {
+		ms.remove(el)
+	}
+	return nil
+}
+
+// Delete removes the dialogue state from the store.
+func (ms *MemoryStore) Delete(id string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.init()
+
+	if el, found := ms.items[id]; found {
+		ms.remove(el)
+	}
+	return nil
+}
+
+// Range invokes fn with a copy of every non-expired dialogue state in the
+// store, in the order of most recent use.
+func (ms *MemoryStore) Range(fn func(state DialogueState) bool) error {
+	ms.mu.Lock()
+	ms.init()
+	var states []DialogueState
+	for el := ms.lru.Front(); el != nil; el = el.Next() {
+		if entry := el.Value.(*memEntry); !ms.expired(entry) {
+			states = append(states, entry.state.Clone())
+		}
+	}
+	ms.mu.Unlock()
+
+	for _, state := range states {
+		if !fn(state) {
+			break
+		}
+	}
+	return nil
+}
+
+// Len returns the number of dialogues currently in the store. Expired entries
+// that have not been evicted yet are included.
+func (ms *MemoryStore) Len() int {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.init()
+	return ms.lru.Len()
+}
+
+func (ms *MemoryStore) expired(entry *memEntry) bool {
+	return ms.TTL > 0 && time.Since(entry.lastUsed) > ms.TTL
+}
+
+func (ms *MemoryStore) remove(el *list.Element) {
+	entry := ms.lru.Remove(el).(*memEntry)
+	delete(ms.items, entry.state.ID)
+}
+
+func (ms *MemoryStore) init() {
+	if ms.items == nil {
+		ms.lru = list.New()
+		ms.items = map[string]*list.Element{}
+	}
+}
@@ -0,0 +1,150 @@
+package snowman_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spy16/snowman"
+)
+
+func TestMemoryStore(t *testing.T) {
+	t.Parallel()
+
+	t.Run("LRU", func(t *testing.T) {
+		ms := &snowman.MemoryStore{MaxSize: 2}
+		mustPut(t, ms, snowman.DialogueState{ID: "a"})
+		mustPut(t, ms, snowman.DialogueState{ID: "b"})
+		if _, err := ms.Get("a"); err != nil {
+			t.Fatalf("Get() unexpected error: %v", err)
+		}
+		mustPut(t, ms, snowman.DialogueState{ID: "c"})
+
+		if _, err := ms.Get("b"); !errors.Is(err, snowman.ErrNotFound) {
+			t.Errorf("Get() expected 'b' to be evicted, got err=%v", err)
+		}
+		if ms.Len() != 2 {
+			t.Errorf("Len() want 2, got %d", ms.Len())
+		}
+	})
+
+	t.Run("TTL", func(t *testing.T) {
+		ms := &snowman.MemoryStore{TTL: 10 * time.Millisecond}
+		mustPut(t, ms, snowman.DialogueState{ID: "a"})
+		time.Sleep(20 * time.Millisecond)
+
+		if _, err := ms.Get("a"); !errors.Is(err, snowman.ErrNotFound) {
+			t.Errorf("Get() expected 'a' to be expired, got err=%v", err)
+		}
+	})
+
+	t.Run("Isolation", func(t *testing.T) {
+		ms := &snowman.MemoryStore{}
+		mustPut(t, ms, snowman.DialogueState{ID: "a", Values: map[string]interface{}{"k": "v"}})
+
+		got, _ := ms.Get("a")
+		got.Values["k"] = "changed"
+
+		again, _ := ms.Get("a")
+		if again.Values["k"] != "v" {
+			t.Errorf("Get() expected stored state to be unaffected, got %v", again.Values["k"])
+		}
+	})
+}
+
+func TestFileStore(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "dialogues.jsonl")
+
+	fs, err := snowman.OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileStore() unexpected error: %v", err)
+	}
+	mustPut(t, fs, snowman.DialogueState{ID: "a", Values: map[string]interface{}{"count": 1}})
+	mustPut(t, fs, snowman.DialogueState{ID: "b", Slots: map[string]interface{}{"city": "Bengaluru"}})
+	mustPut(t, fs, snowman.DialogueState{ID: "a", Values: map[string]interface{}{"count": 2}})
+	if err := fs.Delete("b"); err != nil {
+		t.Fatalf("Delete() unexpected error: %v", err)
+	}
+	if err := fs.Close(); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+
+	reopened, err := snowman.OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileStore() unexpected error on reopen: %v", err)
+	}
+	defer reopened.Close()
+
+	a, err := reopened.Get("a")
+	if err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	if a.Values["count"] != float64(2) {
+		t.Errorf("Get() want count=2, got %v", a.Values["count"])
+	}
+
+	if _, err := reopened.Get("b"); !errors.Is(err, snowman.ErrNotFound) {
+		t.Errorf("Get() expected 'b' to be deleted, got err=%v", err)
+	}
+}
+
+func TestFileStore_CompactionFailure(t *testing.T) {
+	t.Parallel()
+
+	if os.Geteuid() == 0 {
+		t.Skip("directory permissions are not enforced for root")
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dialogues.jsonl")
+	fs, err := snowman.OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileStore() unexpected error: %v", err)
+	}
+	defer fs.Close()
+	logger := &recordingLogger{}
+	fs.Logger = logger
+
+	// compaction needs to create a new file in the directory, which fails
+	// once the directory is read-only.
+	if err := os.Chmod(dir, 0o500); err != nil {
+		t.Fatalf("failed to make directory read-only: %v", err)
+	}
+	defer os.Chmod(dir, 0o700)
+
+	// the records are stored even if the compaction fails, so the failure
+	// is only logged.
+	for i := 0; i < 1100; i++ {
+		mustPut(t, fs, snowman.DialogueState{ID: "a", Values: map[string]interface{}{"count": i}})
+	}
+	if !strings.Contains(logger.String(), "failed to compact") {
+		t.Fatalf("want compaction failure in a read-only directory logged, got %q", logger.String())
+	}
+
+	if err := fs.Close(); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+	_ = os.Chmod(dir, 0o700)
+
+	reopened, err := snowman.OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileStore() unexpected error on reopen: %v", err)
+	}
+	defer reopened.Close()
+
+	if a, err := reopened.Get("a"); err != nil || a.Values["count"] != float64(1099) {
+		t.Errorf("Get() want count=1099 after reopen, got %v (err=%v)", a, err)
+	}
+}
+
+func mustPut(t *testing.T, store snowman.DialogueStore, state snowman.DialogueState) {
+	t.Helper()
+	if err := store.Put(state); err != nil {
+		t.Fatalf("Put() unexpected error: %v", err)
+	}
+}
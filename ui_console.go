@@ -6,8 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 var _ UI = (*ConsoleUI)(nil)
@@ -19,6 +21,12 @@ type ConsoleUI struct {
 
 	once    sync.Once
 	scanner *bufio.Scanner
+
+	// choices holds the buttons/quick replies of the last message said so
+	// that the user can pick one by entering its number.
+	mu      sync.Mutex
+	choices []QuickReply
+	lastID  int
 }
 
 func (cui *ConsoleUI) Listen(ctx context.Context, handle func(msg Msg)) error {
@@ -42,16 +50,90 @@ func (cui *ConsoleUI) Listen(ctx context.Context, handle func(msg Msg)) error {
 			if !cui.scanner.Scan() {
 				return nil
 			}
-			handle(Msg{
-				From: User{ID: "user"},
-				Body: cui.scanner.Text(),
-			})
+			handle(cui.toMsg(cui.scanner.Text()))
 		}
 	}
 }
 
+// Say renders the message to the console. Attachments are rendered as links
+// and the buttons and quick replies as a numbered list of choices.
 func (cui *ConsoleUI) Say(_ context.Context, msg Msg) error {
+	text, choices := renderText(msg)
+	if len(choices) > 0 {
+		cui.mu.Lock()
+		cui.choices = choices
+		cui.mu.Unlock()
+	}
+
 	fmt.Print("\r" + strings.Repeat(" ", len(cui.Prompt)+5))
-	fmt.Println("\r" + msg.Body)
+	fmt.Println("\r" + text)
 	return nil
 }
+
+// toMsg creates a message from the user input. If the input is the number of
+// one of the choices offered in the last message, the message carries the
+// choice.
+func (cui *ConsoleUI) toMsg(text string) Msg {
+	cui.mu.Lock()
+	defer cui.mu.Unlock()
+
+	cui.lastID++
+	msg := Msg{
+		ID:          strconv.Itoa(cui.lastID),
+		At:          time.Now(),
+		From:        User{ID: "user"},
+		Body:        text,
+		Channel:     "console",
+		ChannelType: ChannelDirect,
+	}
+
+	pickChoice(&msg, cui.choices)
+	cui.choices = nil
+	return msg
+}
+
+// renderText renders the message as plain text. Attachments are rendered as
+// links and the buttons and quick replies as a numbered list of choices which
+// are returned for use with pickChoice.
+func renderText(msg Msg) (string, []QuickReply) {
+	var sb strings.Builder
+	sb.WriteString(msg.Body)
+	for _, block := range msg.Blocks {
+		sb.WriteString("\n" + block)
+	}
+
+	for _, att := range msg.Attachments {
+		title := att.Title
+		if title == "" {
+			title = att.Name
+		}
+		sb.WriteString(fmt.Sprintf("\n[%s] %s %s", att.Type, title, att.URL))
+	}
+
+	var choices []QuickReply
+	for _, btn := range msg.Buttons {
+		if btn.URL != "" {
+			sb.WriteString(fmt.Sprintf("\n  - %s: %s", btn.Label, btn.URL))
+			continue
+		}
+		choices = append(choices, QuickReply{Label: btn.Label, Payload: btn.Payload})
+	}
+	choices = append(choices, msg.QuickReplies...)
+	for i, choice := range choices {
+		sb.WriteString(fmt.Sprintf("\n  %d) %s", i+1, choice.Label))
+	}
+
+	return sb.String(), choices
+}
+
+// pickChoice replaces the body of the message with the label and payload of
+// the choice if the body is the number of one of the choices.
+func pickChoice(msg *Msg, choices []QuickReply) {
+	n, err := strconv.Atoi(strings.TrimSpace(msg.Body))
+	if err != nil || n < 1 || n > len(choices) {
+		return
+	}
+
+	msg.Body = choices[n-1].Label
+	msg.Payload = choices[n-1].Payload
+}
@@ -0,0 +1,91 @@
+package snowman_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spy16/snowman"
+)
+
+func TestRenderText(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		title   string
+		msg     snowman.Msg
+		want    string
+		choices []snowman.QuickReply
+	}{
+		{
+			title: "Plain",
+			msg:   snowman.Msg{Body: "hello"},
+			want:  "hello",
+		},
+		{
+			title: "Blocks",
+			msg:   snowman.Msg{Body: "hello", Blocks: []string{"first", "second"}},
+			want:  "hello\nfirst\nsecond",
+		},
+		{
+			title: "Attachments",
+			msg: snowman.Msg{Body: "files", Attachments: []snowman.Attachment{
+				{Type: snowman.AttachmentImage, URL: "http://x/a.png", Title: "Chart"},
+				{Type: snowman.AttachmentFile, URL: "http://x/b.pdf", Name: "b.pdf"},
+			}},
+			want: "files\n[image] Chart http://x/a.png\n[file] b.pdf http://x/b.pdf",
+		},
+		{
+			title: "Choices",
+			msg: snowman.Msg{
+				Body: "pick",
+				Buttons: []snowman.Button{
+					{Label: "Docs", URL: "http://x/docs"},
+					{Label: "Yes", Payload: "yes"},
+				},
+				QuickReplies: []snowman.QuickReply{{Label: "No"}},
+			},
+			want:    "pick\n  - Docs: http://x/docs\n  1) Yes\n  2) No",
+			choices: []snowman.QuickReply{{Label: "Yes", Payload: "yes"}, {Label: "No"}},
+		},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.title, func(t *testing.T) {
+			got, choices := snowman.RenderText(tt.msg)
+			if got != tt.want {
+				t.Errorf("want text %q, got %q", tt.want, got)
+			}
+			if !reflect.DeepEqual(choices, tt.choices) {
+				t.Errorf("want choices %v, got %v", tt.choices, choices)
+			}
+		})
+	}
+}
+
+func TestPickChoice(t *testing.T) {
+	t.Parallel()
+
+	choices := []snowman.QuickReply{{Label: "Tea", Payload: "tea"}, {Label: "Coffee"}}
+	table := []struct {
+		body        string
+		wantBody    string
+		wantPayload string
+	}{
+		{body: "1", wantBody: "Tea", wantPayload: "tea"},
+		{body: " 2 ", wantBody: "Coffee"},
+		{body: "0", wantBody: "0"},
+		{body: "3", wantBody: "3"},
+		{body: "tea", wantBody: "tea"},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.body, func(t *testing.T) {
+			msg := snowman.Msg{Body: tt.body}
+			snowman.PickChoice(&msg, choices)
+			if msg.Body != tt.wantBody || msg.Payload != tt.wantPayload {
+				t.Errorf("want body '%s' and payload '%s', got '%s' and '%s'",
+					tt.wantBody, tt.wantPayload, msg.Body, msg.Payload)
+			}
+		})
+	}
+}
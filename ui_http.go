@@ -0,0 +1,237 @@
+package snowman
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	_ UI           = (*HTTPUI)(nil)
+	_ http.Handler = (*HTTPUI)(nil)
+)
+
+const (
+	defaultHTTPTimeout  = 30 * time.Second
+	httpShutdownTimeout = 5 * time.Second
+	maxHTTPBodySize     = 1 << 20
+)
+
+// HTTPUI implements a webhook style UI. Messages are received as JSON encoded
+// Msg values POSTed to the UI. Replies are returned in the response body as
+// '{"replies": [<Msg>...]}' once the bot is done handling the message (or the
+// Timeout is reached). If CallbackURL is set, the request is acknowledged with
+// '202 Accepted' instead and every reply is POSTed as a JSON Msg to the URL.
+// Replies said outside of a request (e.g., scheduled messages) are also sent
+// to the CallbackURL.
+//
+// If Addr is set, Listen starts an HTTP server on it. Otherwise the HTTPUI can
+// be mounted on an existing server as an http.Handler while Listen is running.
+type HTTPUI struct {
+	Logger
+
+	Addr        string
+	CallbackURL string
+	Timeout     time.Duration
+	Client      *http.Client
+
+	mu      sync.Mutex
+	receive func(msg Msg)
+	pending map[string]*httpExchange
+	lastID  int64
+}
+
+type httpExchange struct {
+	mu      sync.Mutex
+	replies []Msg
+}
+
+type httpResponse struct {
+	ID      string `json:"id"`
+	Replies []Msg  `json:"replies,omitempty"`
+}
+
+// Say delivers the message to the request it is a reply to, or POSTs it to
+// the CallbackURL if the request is not pending anymore.
+func (hui *HTTPUI) Say(ctx context.Context, msg Msg) error {
+	reqID, _ := msg.To.Attribs["http_request"].(string)
+
+	hui.mu.Lock()
+	ex, found := hui.pending[reqID]
+	hui.mu.Unlock()
+
+	if found {
+		ex.mu.Lock()
+		ex.replies = append(ex.replies, msg)
+		ex.mu.Unlock()
+		return nil
+	}
+
+	if hui.CallbackURL == "" {
+		return errors.New("no pending request or callback url to deliver the message")
+	}
+	return hui.callback(ctx, msg)
+}
+
+// Listen makes the UI ready to accept messages and blocks until the context
+// is cancelled.
+func (hui *HTTPUI) Listen(ctx context.Context, handle func(msg Msg)) error {
+	if hui.Logger == nil {
+		hui.Logger = NoOpLogger{}
+	}
+
+	hui.mu.Lock()
+	hui.receive = handle
+	hui.pending = map[string]*httpExchange{}
+	hui.mu.Unlock()
+
+	defer func() {
+		hui.mu.Lock()
+		hui.receive = nil
+		hui.mu.Unlock()
+	}()
+
+	if hui.Addr != "" {
+		hui.Infof("listening for http messages on '%s'", hui.Addr)
+	}
+	return serveUntilDone(ctx, hui.Addr, hui)
+}
+
+// serveUntilDone serves h on addr until the ctx is cancelled and then shuts
+// the server down gracefully. If addr is empty, it only waits for the ctx to
+// be cancelled since h is expected to be mounted on an existing server.
+func serveUntilDone(ctx context.Context, addr string, h http.Handler) error {
+	if addr == "" {
+		<-ctx.Done()
+		return nil
+	}
+
+	srv := &http.Server{Addr: addr, Handler: h}
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.ListenAndServe() }()
+
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), httpShutdownTimeout)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+
+	case err := <-errCh:
+		return err
+	}
+}
+
+// ServeHTTP accepts a JSON encoded Msg and delivers it to the bot.
+func (hui *HTTPUI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var msg Msg
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxHTTPBodySize)).Decode(&msg); err != nil {
+		http.Error(w, fmt.Sprintf("invalid message: %v", err), http.StatusBadRequest)
+		return
+	} else if msg.From.ID == "" {
+		http.Error(w, "invalid message: from.id must be set", http.StatusBadRequest)
+		return
+	}
+
+	hui.mu.Lock()
+	receive := hui.receive
+	hui.lastID++
+	reqID := strconv.FormatInt(hui.lastID, 10)
+	hui.mu.Unlock()
+
+	if receive == nil {
+		http.Error(w, "not listening", http.StatusServiceUnavailable)
+		return
+	}
+
+	if msg.ID == "" {
+		msg.ID = reqID
+	}
+	if msg.At.IsZero() {
+		msg.At = time.Now()
+	}
+	msg.From.Attribs = cloneMerge(msg.From.Attribs, map[string]interface{}{"http_request": reqID})
+
+	if hui.CallbackURL != "" {
+		receive(msg)
+		writeJSON(w, http.StatusAccepted, httpResponse{ID: msg.ID})
+		return
+	}
+
+	ex := &httpExchange{}
+	done := make(chan struct{})
+	msg.done = func() { close(done) }
+
+	hui.mu.Lock()
+	hui.pending[reqID] = ex
+	hui.mu.Unlock()
+
+	receive(msg)
+
+	timeout := hui.Timeout
+	if timeout <= 0 {
+		timeout = defaultHTTPTimeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+	case <-timer.C:
+		hui.Warnf("timed out waiting for replies to message '%s'", msg.ID)
+	case <-r.Context().Done():
+	}
+
+	hui.mu.Lock()
+	delete(hui.pending, reqID)
+	hui.mu.Unlock()
+
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+	writeJSON(w, http.StatusOK, httpResponse{ID: msg.ID, Replies: ex.replies})
+}
+
+func (hui *HTTPUI) callback(ctx context.Context, msg Msg) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hui.CallbackURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := hui.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("callback failed with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,172 @@
+package snowman_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spy16/snowman"
+)
+
+func TestHTTPUI(t *testing.T) {
+	t.Parallel()
+
+	echo := snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+		if err := di.Say(msg.Context(), "you said:"); err != nil {
+			return err
+		}
+		return di.Say(msg.Context(), msg.Body)
+	})
+
+	t.Run("Sync", func(t *testing.T) {
+		ui := &snowman.HTTPUI{}
+		srv := httptest.NewServer(ui)
+		defer srv.Close()
+		runBot(t, ui, echo)
+
+		resp := postMsg(t, srv.URL, `{"from": {"id": "alice"}, "body": "hello"}`)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("POST want status 200, got %d", resp.StatusCode)
+		}
+
+		var got struct {
+			Replies []snowman.Msg `json:"replies"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+
+		if len(got.Replies) != 2 || got.Replies[1].Body != "hello" || got.Replies[1].To.ID != "alice" {
+			t.Errorf("POST want 2 replies to alice ending with 'hello', got %+v", got.Replies)
+		}
+	})
+
+	t.Run("Async", func(t *testing.T) {
+		received := make(chan snowman.Msg, 2)
+		callback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var msg snowman.Msg
+			_ = json.NewDecoder(r.Body).Decode(&msg)
+			received <- msg
+		}))
+		defer callback.Close()
+
+		ui := &snowman.HTTPUI{CallbackURL: callback.URL}
+		srv := httptest.NewServer(ui)
+		defer srv.Close()
+		runBot(t, ui, echo)
+
+		resp := postMsg(t, srv.URL, `{"from": {"id": "bob"}, "body": "hi"}`)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusAccepted {
+			t.Fatalf("POST want status 202, got %d", resp.StatusCode)
+		}
+
+		for _, want := range []string{"you said:", "hi"} {
+			select {
+			case msg := <-received:
+				if msg.Body != want {
+					t.Errorf("callback want body '%s', got '%s'", want, msg.Body)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("callback not invoked for '%s'", want)
+			}
+		}
+	})
+
+	t.Run("Dropped", func(t *testing.T) {
+		ui := &snowman.HTTPUI{Timeout: 10 * time.Second}
+		srv := httptest.NewServer(ui)
+		defer srv.Close()
+
+		started := make(chan struct{}, 1)
+		stop := startBot(t, &snowman.Bot{
+			UI:              ui,
+			ShutdownTimeout: 50 * time.Millisecond,
+			Handler: snowman.Fn(func(msg *snowman.Msg, _ snowman.Dialogue) error {
+				started <- struct{}{}
+				<-msg.Context().Done()
+				return nil
+			}),
+		})
+
+		// wait for the UI to start listening.
+		postMsg(t, srv.URL, `{}`).Body.Close()
+
+		// the second message queues behind the first one, which blocks until
+		// the shutdown deadline. it is dropped then and must not hang.
+		finished := make(chan struct{}, 2)
+		for i := 0; i < 2; i++ {
+			go func() {
+				resp, err := http.Post(srv.URL, "application/json", strings.NewReader(`{"from": {"id": "carol"}, "body": "hi"}`))
+				if err == nil {
+					resp.Body.Close()
+				}
+				finished <- struct{}{}
+			}()
+			if i == 0 {
+				waitN(t, started, 1)
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+
+		if err := stop(); err != nil {
+			t.Fatalf("Run() unexpected error: %v", err)
+		}
+		waitN(t, finished, 2)
+	})
+
+	t.Run("InvalidMsg", func(t *testing.T) {
+		ui := &snowman.HTTPUI{}
+		srv := httptest.NewServer(ui)
+		defer srv.Close()
+		runBot(t, ui, echo)
+
+		resp := postMsg(t, srv.URL, `{"body": "no sender"}`)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("POST want status 400, got %d", resp.StatusCode)
+		}
+	})
+}
+
+// runBot runs a bot with the UI and handler until the test ends.
+func runBot(t *testing.T, ui snowman.UI, h snowman.Handler) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		bot := snowman.Bot{UI: ui, Handler: h, Logger: snowman.NoOpLogger{}}
+		_ = bot.Run(ctx)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+// postMsg posts the body, retrying while the UI is not listening yet.
+func postMsg(t *testing.T, url, body string) *http.Response {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		resp, err := http.Post(url, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST failed: %v", err)
+		}
+
+		if resp.StatusCode != http.StatusServiceUnavailable || time.Now().After(deadline) {
+			return resp
+		}
+		resp.Body.Close()
+		time.Sleep(10 * time.Millisecond)
+	}
+}
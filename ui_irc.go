@@ -0,0 +1,445 @@
+package snowman
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var _ UI = (*IRCUI)(nil)
+
+const (
+	ircMaxLineLen   = 400
+	ircBurst        = 5
+	ircLineInterval = 500 * time.Millisecond
+	ircWriteTimeout = 10 * time.Second
+
+	// ircNickAttempts is the number of alternative nicks tried when the
+	// nick is already in use.
+	ircNickAttempts = 3
+
+	// ircFormatting holds the control characters used for bold, colour,
+	// reset, monospace, reverse, italic, strikethrough and underline.
+	ircFormatting = "\x02\x03\x0f\x11\x16\x1d\x1e\x1f"
+)
+
+// IRCUI implements an IRC client UI. It connects to the server at Addr,
+// joins the Channels and responds to messages in channels that are addressed
+// to its nick (e.g., 'snowy: hello') and to all private messages. If the nick
+// is in use, underscores are appended to it until the server accepts it. The
+// connection is re-established if it fails.
+type IRCUI struct {
+	Logger
+
+	Addr     string
+	Nick     string
+	User     string
+	RealName string
+	Password string
+	Channels []string
+
+	// TLS enables TLS for the connection if not nil.
+	TLS *tls.Config
+
+	mu      sync.Mutex
+	conn    *ircConn
+	choices map[string][]QuickReply
+	lastID  int
+}
+
+// ircMessage is a parsed IRC protocol line.
+type ircMessage struct {
+	Prefix  string
+	Command string
+	Params  []string
+}
+
+// nick returns the nick part of the message prefix.
+func (im ircMessage) nick() string {
+	if i := strings.IndexAny(im.Prefix, "!@"); i >= 0 {
+		return im.Prefix[:i]
+	}
+	return im.Prefix
+}
+
+type ircConn struct {
+	net.Conn
+
+	mu     sync.Mutex
+	tokens int
+	refill time.Time
+}
+
+// Say sends the message to the channel or the user it is a reply to. In
+// channels, the message is addressed to the user by prefixing the nick.
+func (iui *IRCUI) Say(_ context.Context, msg Msg) error {
+	target, ok := msg.To.Attribs["irc_target"].(string)
+	if !ok {
+		return errors.New("irc_target attrib missing")
+	}
+
+	text, choices := renderText(msg)
+	if len(choices) > 0 {
+		iui.mu.Lock()
+		if iui.choices == nil {
+			iui.choices = map[string][]QuickReply{}
+		}
+		iui.choices[msg.To.ID] = choices
+		iui.mu.Unlock()
+	}
+
+	iui.mu.Lock()
+	conn := iui.conn
+	iui.mu.Unlock()
+	if conn == nil {
+		return errors.New("not connected")
+	}
+
+	prefix := ""
+	if isIRCChannel(target) && msg.To.ID != "" {
+		prefix = msg.To.ID + ": "
+	}
+
+	for _, line := range ircLines(text) {
+		for _, part := range splitText(prefix+line, ircMaxLineLen) {
+			if err := conn.send("PRIVMSG " + target + " :" + part); err != nil {
+				return err
+			}
+		}
+		prefix = ""
+	}
+	return nil
+}
+
+// Listen connects to the server and handles the messages until the ctx is
+// cancelled. The connection is re-established with backoff if it fails, and
+// an error is returned if it cannot be established in maxConnectAttempts.
+func (iui *IRCUI) Listen(ctx context.Context, handle func(msg Msg)) error {
+	if iui.Logger == nil {
+		iui.Logger = NoOpLogger{}
+	}
+	if iui.Nick == "" {
+		return errors.New("nick must be set")
+	}
+
+	failures := 0
+	for {
+		iui.Infof("connecting to '%s' [attempt=%d]...", iui.Addr, failures+1)
+		registered, err := iui.session(ctx, handle)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if registered {
+			failures = 0
+		} else if failures++; failures >= maxConnectAttempts {
+			return fmt.Errorf("failed to connect even after %d attempts: %w", failures, err)
+		}
+		iui.Warnf("disconnected from '%s': %v", iui.Addr, err)
+
+		if err := sleepUntil(ctx, time.Now().Add(time.Duration(failures)*time.Second)); err != nil {
+			return nil
+		}
+	}
+}
+
+// session handles a single connection to the server. Returns true if the
+// registration with the server succeeded.
+func (iui *IRCUI) session(ctx context.Context, handle func(msg Msg)) (bool, error) {
+	conn, err := iui.dial(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.send("QUIT :bye")
+			_ = conn.Close()
+		case <-stop:
+		}
+	}()
+
+	user := iui.User
+	if user == "" {
+		user = iui.Nick
+	}
+	realName := iui.RealName
+	if realName == "" {
+		realName = iui.Nick
+	}
+
+	nick := iui.Nick
+	if iui.Password != "" {
+		_ = conn.send("PASS " + iui.Password)
+	}
+	_ = conn.send("NICK " + nick)
+	if err := conn.send("USER " + user + " 0 * :" + realName); err != nil {
+		return false, err
+	}
+
+	defer func() {
+		iui.mu.Lock()
+		iui.conn = nil
+		iui.mu.Unlock()
+	}()
+
+	registered, alternatives := false, 0
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		im, ok := parseIRC(scanner.Text())
+		if !ok {
+			continue
+		}
+
+		switch im.Command {
+		case "PING":
+			_ = conn.send("PONG :" + im.last())
+
+		case "001":
+			registered = true
+			nick = im.Params[0]
+			iui.mu.Lock()
+			iui.conn = conn
+			iui.mu.Unlock()
+			iui.Infof("registered as '%s'", nick)
+
+			if len(iui.Channels) > 0 {
+				_ = conn.send("JOIN " + strings.Join(iui.Channels, ","))
+			}
+
+		case "432":
+			if !registered {
+				return false, fmt.Errorf("nick '%s' rejected by the server: %s", nick, im.last())
+			}
+
+		case "433", "436", "437":
+			if registered {
+				continue
+			}
+			if alternatives >= ircNickAttempts {
+				return false, fmt.Errorf("nick '%s' is not available: %s", nick, im.last())
+			}
+
+			alternatives++
+			nick += "_"
+			iui.Infof("nick is not available, trying '%s'", nick)
+			_ = conn.send("NICK " + nick)
+
+		case "NICK":
+			if strings.EqualFold(im.nick(), nick) {
+				nick = im.last()
+			}
+
+		case "PRIVMSG":
+			if len(im.Params) < 2 {
+				continue
+			}
+			if msg, ok := iui.toMsg(nick, im); ok {
+				handle(msg)
+			}
+
+		case "ERROR":
+			return registered, fmt.Errorf("server error: %s", im.last())
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return registered, err
+	}
+	return registered, errors.New("connection closed")
+}
+
+func (iui *IRCUI) dial(ctx context.Context) (*ircConn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", iui.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if iui.TLS != nil {
+		cfg := iui.TLS.Clone()
+		if cfg.ServerName == "" {
+			cfg.ServerName, _, _ = net.SplitHostPort(iui.Addr)
+		}
+
+		tlsConn := tls.Client(conn, cfg)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	return &ircConn{Conn: conn, tokens: ircBurst}, nil
+}
+
+// toMsg creates a message from the PRIVMSG if it is a private message or is
+// addressed to the nick in a channel.
+func (iui *IRCUI) toMsg(nick string, im ircMessage) (Msg, bool) {
+	target, text := im.Params[0], im.last()
+	if strings.HasPrefix(text, "\x01") {
+		return Msg{}, false // CTCP
+	}
+
+	from := im.nick()
+	replyTo, chType := from, ChannelDirect
+	if isIRCChannel(target) {
+		body, addressed := stripIRCAddress(nick, text)
+		if !addressed {
+			return Msg{}, false
+		}
+		text, replyTo, chType = body, target, ChannelPublic
+	}
+
+	iui.mu.Lock()
+	defer iui.mu.Unlock()
+
+	iui.lastID++
+	msg := Msg{
+		ID:  strconv.Itoa(iui.lastID),
+		At:  time.Now(),
+		Raw: im,
+		From: User{
+			ID:      from,
+			Name:    from,
+			Attribs: map[string]interface{}{"irc_target": replyTo, "irc_prefix": im.Prefix},
+		},
+		Body:        text,
+		Channel:     replyTo,
+		ChannelType: chType,
+	}
+
+	pickChoice(&msg, iui.choices[from])
+	delete(iui.choices, from)
+	return msg, true
+}
+
+// send writes the line to the server. Lines are throttled after a short burst
+// to avoid being disconnected for flooding.
+func (ic *ircConn) send(line string) error {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+
+	now := time.Now()
+	if !ic.refill.IsZero() {
+		ic.tokens += int(now.Sub(ic.refill) / ircLineInterval)
+		if ic.tokens > ircBurst {
+			ic.tokens = ircBurst
+		}
+	}
+
+	if ic.tokens <= 0 {
+		time.Sleep(ircLineInterval)
+		ic.tokens = 1
+		now = time.Now()
+	}
+	ic.tokens--
+	ic.refill = now
+
+	_ = ic.SetWriteDeadline(time.Now().Add(ircWriteTimeout))
+	_, err := ic.Write([]byte(line + "\r\n"))
+	return err
+}
+
+func (im ircMessage) last() string {
+	if len(im.Params) == 0 {
+		return ""
+	}
+	return im.Params[len(im.Params)-1]
+}
+
+// parseIRC parses a line of the IRC protocol. Message tags are ignored.
+func parseIRC(line string) (ircMessage, bool) {
+	line = strings.TrimRight(line, "\r\n")
+	if strings.HasPrefix(line, "@") {
+		i := strings.IndexByte(line, ' ')
+		if i < 0 {
+			return ircMessage{}, false
+		}
+		line = line[i+1:]
+	}
+
+	var im ircMessage
+	if strings.HasPrefix(line, ":") {
+		i := strings.IndexByte(line, ' ')
+		if i < 0 {
+			return ircMessage{}, false
+		}
+		im.Prefix, line = line[1:i], line[i+1:]
+	}
+
+	trailing := ""
+	hasTrailing := false
+	if i := strings.Index(line, " :"); i >= 0 {
+		trailing, hasTrailing = line[i+2:], true
+		line = line[:i]
+	} else if strings.HasPrefix(line, ":") {
+		trailing, hasTrailing, line = line[1:], true, ""
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ircMessage{}, false
+	}
+
+	im.Command = strings.ToUpper(fields[0])
+	im.Params = fields[1:]
+	if hasTrailing {
+		im.Params = append(im.Params, trailing)
+	}
+	return im, true
+}
+
+// stripIRCAddress returns the text without the nick if it is addressed to the
+// nick (e.g., 'snowy: hi', 'snowy, hi' or 'snowy hi').
+func stripIRCAddress(nick, text string) (string, bool) {
+	if len(text) <= len(nick) || !strings.EqualFold(text[:len(nick)], nick) {
+		return "", false
+	}
+
+	rest := text[len(nick):]
+	switch rest[0] {
+	case ':', ',', ' ':
+		return strings.TrimSpace(rest[1:]), true
+	default:
+		return "", false
+	}
+}
+
+// ircLines splits the text into the non-empty lines to be sent. Both CR and
+// LF break lines and other control characters, except the ones used for text
+// formatting, are removed so that the text can never end the command early or
+// be taken as a CTCP request.
+func ircLines(text string) []string {
+	text = strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(text)
+
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Map(func(r rune) rune {
+			if (r < 0x20 && !strings.ContainsRune(ircFormatting, r)) || r == 0x7f {
+				return -1
+			}
+			return r
+		}, line)
+
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func isIRCChannel(target string) bool {
+	return target != "" && strings.ContainsRune("#&+!", rune(target[0]))
+}
@@ -0,0 +1,138 @@
+package snowman_test
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spy16/snowman"
+)
+
+func TestIRCUI(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	ui := &snowman.IRCUI{Addr: ln.Addr().String(), Nick: "snowy", Channels: []string{"#test"}}
+	runBot(t, ui, snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+		if msg.Body == "inject" {
+			return di.Say(msg.Context(), "one\rQUIT :bye\x00\x01\r\n\ntwo")
+		}
+		return di.Say(msg.Context(), "you said "+msg.Body)
+	}))
+
+	srv := acceptIRC(t, ln)
+	srv.expect("NICK snowy")
+	srv.expect("USER snowy 0 * :snowy")
+	srv.send(":irc.test 433 * snowy :Nickname is already in use")
+	srv.expect("NICK snowy_")
+	srv.send(":irc.test 001 snowy_ :Welcome")
+	srv.expect("JOIN #test")
+
+	srv.send("PING :abc123")
+	srv.expect("PONG :abc123")
+
+	srv.send(":alice!a@host PRIVMSG #test :snowy_: hello")
+	srv.expect("PRIVMSG #test :alice: you said hello")
+
+	srv.send(":alice!a@host PRIVMSG #test :not for the bot")
+	srv.send(":bob!b@host PRIVMSG snowy_ :hi there")
+	srv.expect("PRIVMSG bob :you said hi there")
+
+	// line breaks and control characters in replies must not inject commands.
+	srv.send(":bob!b@host PRIVMSG snowy_ :inject")
+	srv.expect("PRIVMSG bob :one")
+	srv.expect("PRIVMSG bob :QUIT :bye")
+	srv.expect("PRIVMSG bob :two")
+
+	// the client must reconnect when the connection is lost.
+	srv.conn.Close()
+	srv = acceptIRC(t, ln)
+	srv.expect("NICK snowy")
+}
+
+func TestIRCUI_Nick(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	ui := &snowman.IRCUI{Addr: ln.Addr().String(), Nick: "snowy"}
+	runBot(t, ui, snowman.Fn(func(*snowman.Msg, snowman.Dialogue) error { return nil }))
+
+	// alternatives to a nick in use are limited.
+	srv := acceptIRC(t, ln)
+	srv.expect("NICK snowy")
+	srv.expect("USER snowy 0 * :snowy")
+	for _, nick := range []string{"snowy", "snowy_", "snowy__"} {
+		srv.send(":irc.test 433 * " + nick + " :Nickname is already in use")
+		srv.expect("NICK " + nick + "_")
+	}
+	srv.send(":irc.test 433 * snowy___ :Nickname is already in use")
+	srv.expectClosed()
+
+	// an erroneous nick cannot be fixed by trying alternatives.
+	srv = acceptIRC(t, ln)
+	srv.expect("NICK snowy")
+	srv.expect("USER snowy 0 * :snowy")
+	srv.send(":irc.test 432 * snowy :Erroneous nickname")
+	srv.expectClosed()
+}
+
+type ircServer struct {
+	t       *testing.T
+	conn    net.Conn
+	scanner *bufio.Scanner
+}
+
+func acceptIRC(t *testing.T, ln net.Listener) *ircServer {
+	t.Helper()
+
+	_ = ln.(*net.TCPListener).SetDeadline(time.Now().Add(3 * time.Second))
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("accept failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &ircServer{t: t, conn: conn, scanner: bufio.NewScanner(conn)}
+}
+
+func (is *ircServer) send(line string) {
+	is.t.Helper()
+	if _, err := is.conn.Write([]byte(line + "\r\n")); err != nil {
+		is.t.Fatalf("write failed: %v", err)
+	}
+}
+
+func (is *ircServer) expect(want string) {
+	is.t.Helper()
+	_ = is.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if !is.scanner.Scan() {
+		is.t.Fatalf("want '%s', read failed: %v", want, is.scanner.Err())
+	}
+	if got := strings.TrimSpace(is.scanner.Text()); got != want {
+		is.t.Fatalf("want '%s', got '%s'", want, got)
+	}
+}
+
+func (is *ircServer) expectClosed() {
+	is.t.Helper()
+	_ = is.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for is.scanner.Scan() {
+		if line := is.scanner.Text(); !strings.HasPrefix(line, "QUIT") {
+			is.t.Fatalf("want connection closed, got '%s'", line)
+		}
+	}
+	if err := is.scanner.Err(); err != nil {
+		is.t.Fatalf("want connection closed, read failed: %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package snowman
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+var (
+	_ UI      = (*MultiUI)(nil)
+	_ Typer   = (*MultiUI)(nil)
+	_ Poster  = (*MultiUI)(nil)
+	_ Updater = (*MultiUI)(nil)
+	_ Reactor = (*MultiUI)(nil)
+)
+
+// MultiUI multiplexes multiple UIs so that a single bot can listen on all of
+// them at once. Messages received are tagged with the name of the UI in the
+// Msg.Origin field and outgoing messages are routed back to the UI named by
+// their Origin.
+type MultiUI struct {
+	UIs map[string]UI
+}
+
+// Say sends the message using the UI identified by msg.Origin.
+func (mui *MultiUI) Say(ctx context.Context, msg Msg) error {
+	ui, err := mui.route(msg.Origin)
+	if err != nil {
+		return err
+	}
+	return ui.Say(ctx, msg)
+}
+
+// Typing forwards to the UI identified by msg.Origin if it implements Typer.
+func (mui *MultiUI) Typing(ctx context.Context, msg Msg) error {
+	ui, err := mui.route(msg.Origin)
+	if err != nil {
+		return err
+	}
+
+	if typer, ok := ui.(Typer); ok {
+		return typer.Typing(ctx, msg)
+	}
+	return ErrNotSupported
+}
+
+// Post forwards to the UI identified by msg.Origin if it implements Poster.
+// Otherwise, the message is sent using Say and empty ID is returned.
+func (mui *MultiUI) Post(ctx context.Context, msg Msg) (string, error) {
+	ui, err := mui.route(msg.Origin)
+	if err != nil {
+		return "", err
+	}
+
+	if poster, ok := ui.(Poster); ok {
+		return poster.Post(ctx, msg)
+	}
+	return "", ui.Say(ctx, msg)
+}
+
+// Update forwards to the UI identified by msg.Origin if it implements Updater.
+func (mui *MultiUI) Update(ctx context.Context, id string, msg Msg) error {
+	ui, err := mui.route(msg.Origin)
+	if err != nil {
+		return err
+	}
+
+	if updater, ok := ui.(Updater); ok {
+		return updater.Update(ctx, id, msg)
+	}
+	return ErrNotSupported
+}
+
+// React forwards to the UI identified by target.Origin if it implements
+// Reactor.
+func (mui *MultiUI) React(ctx context.Context, target Msg, emoji string) error {
+	ui, err := mui.route(target.Origin)
+	if err != nil {
+		return err
+	}
+
+	if reactor, ok := ui.(Reactor); ok {
+		return reactor.React(ctx, target, emoji)
+	}
+	return ErrNotSupported
+}
+
+func (mui *MultiUI) route(origin string) (UI, error) {
+	ui, found := mui.UIs[origin]
+	if !found {
+		return nil, fmt.Errorf("no UI for origin '%s'", origin)
+	}
+	return ui, nil
+}
+
+// Listen starts listening on all the UIs concurrently and blocks until all of
+// them return. If any of the UIs fails, the others are stopped and the error
+// is returned.
+func (mui *MultiUI) Listen(ctx context.Context, handle func(msg Msg)) error {
+	if len(mui.UIs) == 0 {
+		return fmt.Errorf("no UIs configured")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(mui.UIs))
+	for name, ui := range mui.UIs {
+		wg.Add(1)
+		go func(name string, ui UI) {
+			defer wg.Done()
+
+			err := ui.Listen(ctx, func(msg Msg) {
+				msg.Origin = name
+				handle(msg)
+			})
+			if err != nil && ctx.Err() == nil {
+				errs <- fmt.Errorf("ui '%s': %w", name, err)
+				cancel()
+			}
+		}(name, ui)
+	}
+	wg.Wait()
+	close(errs)
+
+	// nil if no UI failed (e.g., all of them stopped since ctx was cancelled).
+	return <-errs
+}
@@ -0,0 +1,87 @@
+package snowman_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spy16/snowman"
+)
+
+func TestMultiUI(t *testing.T) {
+	t.Parallel()
+
+	echo := snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+		return di.Say(msg.Context(), "echo: "+msg.Body)
+	})
+
+	t.Run("Routing", func(t *testing.T) {
+		a, b := newTestUI(), newTestUI()
+		stop := startBot(t, &snowman.Bot{
+			UI:      &snowman.MultiUI{UIs: map[string]snowman.UI{"a": a, "b": b}},
+			Handler: echo,
+		})
+
+		// the same user ID on different UIs must be kept apart.
+		a.in <- userMsg("alice", "from a")
+		b.in <- userMsg("alice", "from b")
+
+		for name, ui := range map[string]*testUI{"a": a, "b": b} {
+			select {
+			case got := <-ui.out:
+				if got.Origin != name || got.Body != "echo: from "+name {
+					t.Errorf("ui '%s' want reply 'echo: from %s' with same origin, got %+v", name, name, got)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("no reply on ui '%s'", name)
+			}
+		}
+
+		if err := stop(); err != nil {
+			t.Errorf("Run() want nil error on shutdown, got %v", err)
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		good := newTestUI()
+		bad := &failingUI{err: errors.New("boom")}
+
+		errCh := make(chan error, 1)
+		go func() {
+			bot := &snowman.Bot{
+				UI:      &snowman.MultiUI{UIs: map[string]snowman.UI{"good": good, "bad": bad}},
+				Handler: echo,
+				Logger:  snowman.NoOpLogger{},
+			}
+			errCh <- bot.Run(context.Background())
+		}()
+
+		// the failure must stop the other UIs and be returned.
+		select {
+		case err := <-errCh:
+			if err == nil || !strings.Contains(err.Error(), "ui 'bad'") || !errors.Is(err, bad.err) {
+				t.Errorf("Run() want error from ui 'bad', got %v", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Run() did not return after a UI failed")
+		}
+	})
+
+	t.Run("NoUIs", func(t *testing.T) {
+		mui := &snowman.MultiUI{}
+		if err := mui.Listen(context.Background(), func(snowman.Msg) {}); err == nil {
+			t.Errorf("Listen() want error with no UIs")
+		}
+	})
+}
+
+// failingUI fails to listen with err.
+type failingUI struct {
+	err error
+}
+
+func (ui *failingUI) Say(context.Context, snowman.Msg) error { return nil }
+
+func (ui *failingUI) Listen(context.Context, func(msg snowman.Msg)) error { return ui.err }
@@ -4,17 +4,56 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/slack-go/slack"
 )
 
-var _ UI = (*SlackUI)(nil)
+var (
+	_ UI      = (*SlackUI)(nil)
+	_ Typer   = (*SlackUI)(nil)
+	_ Poster  = (*SlackUI)(nil)
+	_ Updater = (*SlackUI)(nil)
+	_ Reactor = (*SlackUI)(nil)
+)
 
 const maxConnectAttempts = 5
 
-// SlackUI implements snowman SlackUI using Slack RTM based API.
+var slackMention = regexp.MustCompile(`<@([UW][A-Z0-9]+)(?:\|([^>]+))?>`)
+
+// Action ID prefixes used for the buttons and quick replies rendered by
+// SlackUI.
+const (
+	slackButtonPrefix = "snowman_button_"
+	slackReplyPrefix  = "snowman_reply_"
+)
+
+// Modes supported by SlackUI for receiving events from Slack.
+const (
+	// SlackRTM receives events over the (deprecated) RTM API.
+	SlackRTM = "rtm"
+
+	// SlackSocketMode receives Events API events over a websocket opened
+	// using the app-level token (AppToken).
+	SlackSocketMode = "socket"
+
+	// SlackEvents receives Events API events as HTTP requests signed using
+	// the SigningSecret.
+	SlackEvents = "events"
+)
+
+// SlackUI implements snowman SlackUI using Slack APIs. Events are received
+// using the RTM API by default. Set Mode to SlackSocketMode or SlackEvents
+// to use Socket Mode or the HTTP Events API instead. All the modes produce
+// messages of the same shape. Slash commands and interactions (e.g., clicks
+// on buttons) are only received in these modes.
+//
+// In SlackEvents mode, Listen starts an HTTP server on Addr if set. Otherwise
+// the SlackUI can be mounted on an existing server as an http.Handler for the
+// Events API request URL while Listen is running.
 type SlackUI struct {
 	Logger
 
@@ -23,47 +62,178 @@ type SlackUI struct {
 	EnableChannel bool
 	ThreadDirect  bool
 
+	Mode          string
+	AppToken      string
+	SigningSecret string
+	Addr          string
+
+	// APIURL overrides the Slack API base URL (e.g., for tests).
+	APIURL string
+
+	// PostInterval is the minimum interval between the messages sent to a
+	// channel. Defaults to 1 second as per the Slack rate limits. Negative
+	// value disables the limit.
+	PostInterval time.Duration
+
+	// CacheTTL is the duration for which the conversation and user info
+	// fetched from Slack are cached. Defaults to 10 minutes. Cached info
+	// is also dropped when Slack reports a change.
+	CacheTTL time.Duration
+
 	client    *slack.Client
 	slRTM     *slack.RTM
 	self      slack.UserDetails
 	connected bool
+
+	mu      sync.Mutex
+	receive func(msg Msg)
+	cache   slackCache
+	lanes   map[string]*slackLane
 }
 
 // Say sends a message to the user/channel on Slack identified using the UserID
-// in the msg.To field.
+// in the msg.To field. Rich messages are rendered using Block Kit with Body as
+// the notification fallback text. Messages longer than what Slack allows are
+// split into multiple messages.
 func (sui *SlackUI) Say(ctx context.Context, msg Msg) error {
+	_, err := sui.Post(ctx, msg)
+	return err
+}
+
+// Post sends the message like Say and returns the timestamp of the message
+// which can be used to Update it. Replies to slash commands and interactions
+// are sent using their response URL, in which case the returned timestamp is
+// empty.
+func (sui *SlackUI) Post(ctx context.Context, msg Msg) (string, error) {
+	channel, ok := msg.To.Attribs["slack_channel"].(string)
+	if !ok {
+		return "", errors.New("slack_channel attrib missing")
+	}
+
+	if responseURL, ok := msg.To.Attribs["slack_response_url"].(string); ok {
+		err := sui.postResponse(ctx, responseURL, msg)
+		if err == nil {
+			return "", nil
+		}
+		sui.Warnf("failed to reply using response url, posting instead: %v", err)
+	}
+
+	// long messages are sent in parts with the rich content in the last one.
+	var ts string
+	parts := splitText(msg.Body, slackMaxTextLen)
+	for i, body := range parts {
+		part := msg
+		if i < len(parts)-1 {
+			part = Msg{Markdown: msg.Markdown}
+		}
+		part.Body = body
+
+		opts := sui.msgOptions(part)
+		if threadTS, ok := msg.To.Attribs["slack_ts"].(string); ok {
+			opts = append(opts, slack.MsgOptionTS(strings.TrimSpace(threadTS)))
+		}
+
+		err := sui.send(ctx, channel, func() (err error) {
+			_, ts, err = sui.client.PostMessageContext(ctx, channel, opts...)
+			return err
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	return ts, nil
+}
+
+// Update replaces the content of the message identified by its timestamp.
+func (sui *SlackUI) Update(ctx context.Context, id string, msg Msg) error {
+	channel, ok := msg.To.Attribs["slack_channel"].(string)
+	if !ok {
+		return errors.New("slack_channel attrib missing")
+	}
+
+	return sui.send(ctx, channel, func() error {
+		_, _, _, err := sui.client.UpdateMessageContext(ctx, channel, id, sui.msgOptions(msg)...)
+		return err
+	})
+}
+
+// Typing sends a typing indicator to the channel of the user. Slack clears
+// the indicator when the next message is posted or after a few seconds. Only
+// supported in SlackRTM mode.
+func (sui *SlackUI) Typing(_ context.Context, msg Msg) error {
+	if sui.slRTM == nil {
+		return ErrNotSupported
+	}
+
 	channel, ok := msg.To.Attribs["slack_channel"].(string)
 	if !ok {
 		return errors.New("slack_channel attrib missing")
 	}
 
+	sui.slRTM.SendMessage(sui.slRTM.NewTypingMessage(channel))
+	return nil
+}
+
+// React adds the emoji reaction to the target message received from Slack.
+func (sui *SlackUI) React(ctx context.Context, target Msg, emoji string) error {
+	if target.Channel == "" || target.ID == "" {
+		return errors.New("target is not a slack message")
+	}
+
+	ref := slack.NewRefToMessage(target.Channel, target.ID)
+	return sui.client.AddReactionContext(ctx, strings.Trim(emoji, ":"), ref)
+}
+
+func (sui *SlackUI) msgOptions(msg Msg) []slack.MsgOption {
 	opts := []slack.MsgOption{
 		slack.MsgOptionAsUser(true),
 		slack.MsgOptionText(msg.Body, false),
 		slack.MsgOptionParse(false),
 	}
 
-	if ts, ok := msg.To.Attribs["slack_ts"].(string); ok {
-		opts = append(opts, slack.MsgOptionTS(strings.TrimSpace(ts)))
+	if blocks := slackBlocks(msg); len(blocks) > 0 {
+		opts = append(opts, slack.MsgOptionBlocks(blocks...))
 	}
-
-	_, _, err := sui.slRTM.PostMessageContext(ctx, channel, opts...)
-	return err
+	return opts
 }
 
+// Listen receives events from Slack using the configured Mode and blocks
+// until the ctx is cancelled or a fatal error occurs. Returns nil once the
+// ctx is cancelled.
 func (sui *SlackUI) Listen(ctx context.Context, handle func(msg Msg)) error {
-	sui.client = slack.New(sui.Token, sui.Options...)
-	sui.slRTM = sui.client.NewRTM()
-	go sui.slRTM.ManageConnection()
-
 	if sui.Logger == nil {
 		sui.Logger = NoOpLogger{}
 	}
 
+	opts := sui.Options
+	if sui.APIURL != "" {
+		opts = append(append([]slack.Option(nil), opts...), slack.OptionAPIURL(sui.APIURL))
+	}
+	sui.client = slack.New(sui.Token, opts...)
+
+	switch sui.Mode {
+	case "", SlackRTM:
+		return sui.listenRTM(ctx, handle)
+
+	case SlackSocketMode:
+		return sui.listenSocketMode(ctx, handle)
+
+	case SlackEvents:
+		return sui.listenEvents(ctx, handle)
+
+	default:
+		return fmt.Errorf("unknown slack mode '%s'", sui.Mode)
+	}
+}
+
+func (sui *SlackUI) listenRTM(ctx context.Context, handle func(msg Msg)) error {
+	sui.slRTM = sui.client.NewRTM()
+	go sui.slRTM.ManageConnection()
+
 	for {
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
+			return nil
 
 		case ev, more := <-sui.slRTM.IncomingEvents:
 			if !more {
@@ -87,27 +257,47 @@ func (sui *SlackUI) Listen(ctx context.Context, handle func(msg Msg)) error {
 				sui.Infof("connected as '%s' (ID: %s)", sui.self.Name, sui.self.ID)
 
 			case *slack.MessageEvent:
-				if e.Hidden || (e.User == sui.self.ID) {
-					continue
-				}
-
-				ch, err := sui.client.GetConversationInfo(e.Channel, false)
-				if err != nil {
-					sui.Warnf("failed to fetch conversation info: %v", err)
-					continue
-				} else if ch.IsChannel && (!sui.EnableChannel || !sui.isAddressedToMe(e)) {
-					continue
-				}
-
-				sui.handleMessageEvent(ch, e, handle)
+				sui.onMessage(e, handle)
 
 			default:
-				sui.Debugf("unhandled event: %v", ev)
+				if !sui.invalidateRTM(ev.Data) {
+					sui.Debugf("unhandled event: %v", ev)
+				}
 			}
 		}
 	}
 }
 
+// identify fetches the identity of the bot user for modes other than RTM.
+func (sui *SlackUI) identify(ctx context.Context) error {
+	resp, err := sui.client.AuthTestContext(ctx)
+	if err != nil {
+		return fmt.Errorf("authentication error: %w", err)
+	}
+
+	sui.self = slack.UserDetails{ID: resp.UserID, Name: resp.User}
+	sui.Infof("authenticated as '%s' (ID: %s)", sui.self.Name, sui.self.ID)
+	return nil
+}
+
+// onMessage filters out the messages that are not meant for the bot and
+// delivers the rest to the handle function.
+func (sui *SlackUI) onMessage(e *slack.MessageEvent, handle func(msg Msg)) {
+	if e.Hidden || (e.User == sui.self.ID) {
+		return
+	}
+
+	ch, err := sui.conversation(context.Background(), e.Channel)
+	if err != nil {
+		sui.Warnf("failed to fetch conversation info: %v", err)
+		return
+	} else if ch.IsChannel && (!sui.EnableChannel || !sui.isAddressedToMe(e)) {
+		return
+	}
+
+	sui.handleMessageEvent(ch, e, handle)
+}
+
 func (sui *SlackUI) handleMessageEvent(ch *slack.Channel, e *slack.MessageEvent, handle func(msg Msg)) {
 	from := User{
 		ID:   e.User,
@@ -116,24 +306,59 @@ func (sui *SlackUI) handleMessageEvent(ch *slack.Channel, e *slack.MessageEvent,
 			"slack_channel": e.Channel,
 		},
 	}
+	sui.describe(context.Background(), &from)
 
+	thread := e.ThreadTimestamp
 	if !ch.IsIM || sui.ThreadDirect {
-		if e.ThreadTimestamp != "" {
-			from.Attribs["slack_ts"] = e.ThreadTimestamp
-		} else if e.EventTimestamp != "" {
-			from.Attribs["slack_ts"] = e.EventTimestamp
-		} else {
-			from.Attribs["slack_ts"] = e.Timestamp
+		// replies go to a thread rooted at the message when it is not part
+		// of one already. so the message is reported as part of that thread.
+		if thread == "" {
+			thread = e.EventTimestamp
+		}
+		if thread == "" {
+			thread = e.Timestamp
 		}
+		from.Attribs["slack_ts"] = thread
 	}
 
 	handle(Msg{
-		At:   time.Now(),
-		From: from,
-		Body: e.Text,
+		ID:          e.Timestamp,
+		At:          time.Now(),
+		From:        from,
+		Body:        e.Text,
+		Channel:     e.Channel,
+		ChannelType: slackChannelType(ch),
+		Thread:      thread,
+		Mentions:    sui.mentions(e.Text),
+		Raw:         e,
 	})
 }
 
+// mentions returns the users (other than the bot) mentioned in the text.
+func (sui *SlackUI) mentions(text string) []User {
+	var users []User
+	for _, match := range slackMention.FindAllStringSubmatch(text, -1) {
+		if match[1] == sui.self.ID {
+			continue
+		}
+		users = append(users, User{ID: match[1], Name: match[2]})
+	}
+	return users
+}
+
+func slackChannelType(ch *slack.Channel) ChannelType {
+	switch {
+	case ch.IsIM:
+		return ChannelDirect
+	case ch.IsMpIM:
+		return ChannelGroup
+	case ch.IsPrivate || ch.IsGroup:
+		return ChannelPrivate
+	default:
+		return ChannelPublic
+	}
+}
+
 func (sui *SlackUI) stripAtAddress(ev *slack.MessageEvent) bool {
 	var prefixes = []string{
 		addressUser(sui.self.ID, ""),
@@ -171,6 +396,81 @@ func (sui *SlackUI) isAddressedToMe(ev *slack.MessageEvent) bool {
 	return false
 }
 
+// slackBlocks renders the rich content of the message as Block Kit blocks.
+// Returns nil for plain text messages.
+func slackBlocks(msg Msg) []slack.Block {
+	if len(msg.Blocks) == 0 && len(msg.Attachments) == 0 &&
+		len(msg.Buttons) == 0 && len(msg.QuickReplies) == 0 && !msg.Markdown {
+		return nil
+	}
+
+	textType := slack.PlainTextType
+	if msg.Markdown {
+		textType = slack.MarkdownType
+	}
+
+	var blocks []slack.Block
+	for _, text := range append([]string{msg.Body}, msg.Blocks...) {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject(textType, text, false, false), nil, nil))
+	}
+
+	for _, att := range msg.Attachments {
+		title := att.Title
+		if title == "" {
+			title = att.Name
+		}
+
+		if att.Type == AttachmentImage {
+			// slack rejects image blocks without an alt text.
+			alt := title
+			var titleObj *slack.TextBlockObject
+			if title != "" {
+				titleObj = slack.NewTextBlockObject(slack.PlainTextType, title, false, false)
+			} else {
+				alt = "image"
+			}
+			blocks = append(blocks, slack.NewImageBlock(att.URL, alt, "", titleObj))
+		} else {
+			if title == "" {
+				title = att.URL
+			}
+			link := fmt.Sprintf("<%s|%s>", att.URL, title)
+			blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, link, false, false), nil, nil))
+		}
+	}
+
+	var elements []slack.BlockElement
+	for i, btn := range msg.Buttons {
+		el := slack.NewButtonBlockElement(fmt.Sprintf("%s%d", slackButtonPrefix, i), payloadOrLabel(btn.Payload, btn.Label),
+			slack.NewTextBlockObject(slack.PlainTextType, btn.Label, true, false))
+		el.URL = btn.URL
+		if btn.Style != "" {
+			el.WithStyle(slack.Style(btn.Style))
+		}
+		elements = append(elements, el)
+	}
+
+	for i, qr := range msg.QuickReplies {
+		elements = append(elements, slack.NewButtonBlockElement(fmt.Sprintf("%s%d", slackReplyPrefix, i),
+			payloadOrLabel(qr.Payload, qr.Label), slack.NewTextBlockObject(slack.PlainTextType, qr.Label, true, false)))
+	}
+
+	if len(elements) > 0 {
+		blocks = append(blocks, slack.NewActionBlock("", elements...))
+	}
+	return blocks
+}
+
+func payloadOrLabel(payload, label string) string {
+	if payload != "" {
+		return payload
+	}
+	return label
+}
+
 // addressUser creates the escape sequence for marking a user in a message.
 func addressUser(userID string, userName string) string {
 	if userName != "" {
@@ -0,0 +1,190 @@
+package snowman
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+const defaultSlackCacheTTL = 10 * time.Minute
+
+// slackCache caches the conversations and users fetched from Slack.
+type slackCache struct {
+	mu       sync.Mutex
+	channels map[string]cachedChannel
+	users    map[string]cachedUser
+}
+
+type cachedChannel struct {
+	channel *slack.Channel
+	at      time.Time
+}
+
+type cachedUser struct {
+	user *slack.User
+	at   time.Time
+}
+
+// conversation returns the conversation info from the cache if available and
+// fetches it from Slack otherwise.
+func (sui *SlackUI) conversation(ctx context.Context, id string) (*slack.Channel, error) {
+	sui.cache.mu.Lock()
+	cached, found := sui.cache.channels[id]
+	sui.cache.mu.Unlock()
+
+	if found && time.Since(cached.at) < sui.cacheTTL() {
+		return cached.channel, nil
+	}
+
+	ch, err := sui.client.GetConversationInfoContext(ctx, id, false)
+	if err != nil {
+		return nil, err
+	}
+
+	sui.cache.mu.Lock()
+	defer sui.cache.mu.Unlock()
+	if sui.cache.channels == nil {
+		sui.cache.channels = map[string]cachedChannel{}
+	}
+	sui.cache.channels[id] = cachedChannel{channel: ch, at: time.Now()}
+	return ch, nil
+}
+
+// userInfo returns the user info from the cache if available and fetches it
+// from Slack otherwise.
+func (sui *SlackUI) userInfo(ctx context.Context, id string) (*slack.User, error) {
+	sui.cache.mu.Lock()
+	cached, found := sui.cache.users[id]
+	sui.cache.mu.Unlock()
+
+	if found && time.Since(cached.at) < sui.cacheTTL() {
+		return cached.user, nil
+	}
+
+	u, err := sui.client.GetUserInfoContext(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	sui.cache.mu.Lock()
+	defer sui.cache.mu.Unlock()
+	if sui.cache.users == nil {
+		sui.cache.users = map[string]cachedUser{}
+	}
+	sui.cache.users[id] = cachedUser{user: u, at: time.Now()}
+	return u, nil
+}
+
+// describe fills the name, display name, email and timezone of the user from
+// the Slack profile. The user is left as is if the profile is not available.
+func (sui *SlackUI) describe(ctx context.Context, user *User) {
+	if user.ID == "" {
+		return
+	}
+
+	u, err := sui.userInfo(ctx, user.ID)
+	if err != nil {
+		sui.Warnf("failed to fetch user info for '%s': %v", user.ID, err)
+		return
+	}
+
+	user.Name = u.Name
+	user.DisplayName = u.Profile.DisplayName
+	if user.DisplayName == "" {
+		user.DisplayName = u.RealName
+	}
+	user.Email = u.Profile.Email
+	user.TimeZone = u.TZ
+}
+
+func (sui *SlackUI) forgetChannel(id string) {
+	sui.cache.mu.Lock()
+	delete(sui.cache.channels, id)
+	sui.cache.mu.Unlock()
+}
+
+func (sui *SlackUI) forgetUser(id string) {
+	sui.cache.mu.Lock()
+	delete(sui.cache.users, id)
+	sui.cache.mu.Unlock()
+}
+
+func (sui *SlackUI) cacheTTL() time.Duration {
+	if sui.CacheTTL > 0 {
+		return sui.CacheTTL
+	}
+	return defaultSlackCacheTTL
+}
+
+// invalidateRTM drops the cached info of the channel or the user changed by
+// the RTM event. Returns false if the event is not a change event.
+func (sui *SlackUI) invalidateRTM(ev interface{}) bool {
+	switch e := ev.(type) {
+	case *slack.ChannelRenameEvent:
+		sui.forgetChannel(e.Channel.ID)
+	case *slack.GroupRenameEvent:
+		sui.forgetChannel(e.Group.ID)
+	case *slack.ChannelArchiveEvent:
+		sui.forgetChannel(e.Channel)
+	case *slack.ChannelUnarchiveEvent:
+		sui.forgetChannel(e.Channel)
+	case *slack.ChannelDeletedEvent:
+		sui.forgetChannel(e.Channel)
+	case *slack.GroupArchiveEvent:
+		sui.forgetChannel(e.Channel)
+	case *slack.GroupUnarchiveEvent:
+		sui.forgetChannel(e.Channel)
+	case *slack.UserChangeEvent:
+		sui.forgetUser(e.User.ID)
+	default:
+		return false
+	}
+	return true
+}
+
+// invalidateEvent drops the cached info of the channel or the user changed by
+// the Events API event. Returns false if the event is not a change event.
+func (sui *SlackUI) invalidateEvent(payload []byte) bool {
+	var outer struct {
+		Event struct {
+			Type    string          `json:"type"`
+			Channel json.RawMessage `json:"channel"`
+			User    json.RawMessage `json:"user"`
+		} `json:"event"`
+	}
+	if err := json.Unmarshal(payload, &outer); err != nil {
+		return false
+	}
+
+	switch ev := outer.Event; ev.Type {
+	case "channel_rename", "channel_archive", "channel_unarchive", "channel_deleted",
+		"channel_shared", "channel_unshared", "channel_convert_to_private",
+		"group_rename", "group_archive", "group_unarchive", "group_deleted":
+		sui.forgetChannel(slackObjectID(ev.Channel))
+
+	case "user_change", "user_profile_changed":
+		sui.forgetUser(slackObjectID(ev.User))
+
+	default:
+		return false
+	}
+	return true
+}
+
+// slackObjectID returns the ID of a channel or user which is sent either as
+// the ID string or as an object with an 'id' field depending on the event.
+func slackObjectID(raw json.RawMessage) string {
+	var id string
+	if err := json.Unmarshal(raw, &id); err == nil {
+		return id
+	}
+
+	var obj struct {
+		ID string `json:"id"`
+	}
+	_ = json.Unmarshal(raw, &obj)
+	return obj.ID
+}
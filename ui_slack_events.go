@@ -0,0 +1,165 @@
+package snowman
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+var _ http.Handler = (*SlackUI)(nil)
+
+// ServeHTTP accepts Events API, slash command and interaction requests from
+// Slack. Requests are verified using the SigningSecret and acknowledged right
+// away while the payload is handled in the background, since Slack gives up
+// on requests that are not acknowledged within 3 seconds. Retried deliveries
+// of events are acknowledged and ignored since every delivery is acknowledged
+// as soon as it is received.
+func (sui *SlackUI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sui.mu.Lock()
+	receive := sui.receive
+	sui.mu.Unlock()
+
+	if receive == nil {
+		http.Error(w, "not listening", http.StatusServiceUnavailable)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxHTTPBodySize))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	verifier, err := slack.NewSecretsVerifier(r.Header, sui.SigningSecret)
+	if err == nil {
+		_, _ = verifier.Write(body)
+		err = verifier.Ensure()
+	}
+	if err != nil {
+		sui.Warnf("rejected events api request: %v", err)
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			http.Error(w, "invalid form", http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		go func() {
+			if err := sui.handleForm(form, receive); err != nil {
+				sui.Warnf("invalid slash command or interaction: %v", err)
+			}
+		}()
+		return
+	}
+
+	if sui.invalidateEvent(body) {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	ev, err := slackevents.ParseEvent(json.RawMessage(body), slackevents.OptionNoVerifyToken())
+	if err != nil {
+		http.Error(w, "invalid event", http.StatusBadRequest)
+		return
+	}
+
+	if ev.Type == slackevents.URLVerification {
+		var challenge slackevents.ChallengeResponse
+		if err := json.Unmarshal(body, &challenge); err != nil {
+			http.Error(w, "invalid challenge", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(challenge.Challenge))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if r.Header.Get("X-Slack-Retry-Num") != "" {
+		return
+	}
+	go sui.handleEventsAPI(ev, receive)
+}
+
+func (sui *SlackUI) listenEvents(ctx context.Context, handle func(msg Msg)) error {
+	if sui.SigningSecret == "" {
+		return errors.New("signing secret must be set for events api mode")
+	}
+
+	if err := sui.identify(ctx); err != nil {
+		return err
+	}
+
+	sui.mu.Lock()
+	sui.receive = handle
+	sui.mu.Unlock()
+
+	defer func() {
+		sui.mu.Lock()
+		sui.receive = nil
+		sui.mu.Unlock()
+	}()
+
+	if sui.Addr != "" {
+		sui.Infof("listening for slack events on '%s'", sui.Addr)
+	}
+	return serveUntilDone(ctx, sui.Addr, sui)
+}
+
+// handleEventsAPI handles the events received over Socket Mode or the HTTP
+// Events API.
+func (sui *SlackUI) handleEventsAPI(ev slackevents.EventsAPIEvent, handle func(msg Msg)) {
+	if ev.Type != slackevents.CallbackEvent {
+		sui.Debugf("unhandled events api event: %s", ev.Type)
+		return
+	}
+
+	switch e := ev.InnerEvent.Data.(type) {
+	case *slackevents.MessageEvent:
+		sui.onMessage(slackMessageEvent(e), handle)
+
+	default:
+		sui.Debugf("unhandled event: %s", ev.InnerEvent.Type)
+	}
+}
+
+// slackMessageEvent converts the Events API message event to the RTM message
+// event so that both are handled the same way.
+func slackMessageEvent(e *slackevents.MessageEvent) *slack.MessageEvent {
+	msg := slack.Msg{
+		Type:            e.Type,
+		User:            e.User,
+		Text:            e.Text,
+		Timestamp:       e.TimeStamp,
+		ThreadTimestamp: e.ThreadTimeStamp,
+		Channel:         e.Channel,
+		EventTimestamp:  string(e.EventTimeStamp),
+		SubType:         e.SubType,
+		BotID:           e.BotID,
+		Username:        e.Username,
+	}
+
+	switch e.SubType {
+	case "message_changed", "message_deleted", "message_replied":
+		msg.Hidden = true
+	}
+
+	return &slack.MessageEvent{Msg: msg}
+}
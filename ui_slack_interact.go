@@ -0,0 +1,215 @@
+package snowman
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// slackResponse is the message posted to the response URL of slash commands
+// and interactions.
+type slackResponse struct {
+	Text            string        `json:"text"`
+	Blocks          []slack.Block `json:"blocks,omitempty"`
+	ThreadTS        string        `json:"thread_ts,omitempty"`
+	ResponseType    string        `json:"response_type"`
+	ReplaceOriginal bool          `json:"replace_original"`
+}
+
+// handleForm handles the form encoded requests sent by Slack for slash
+// commands and interactions.
+func (sui *SlackUI) handleForm(form url.Values, handle func(msg Msg)) error {
+	if payload := form.Get("payload"); payload != "" {
+		var cb slack.InteractionCallback
+		if err := json.Unmarshal([]byte(payload), &cb); err != nil {
+			return err
+		}
+		sui.handleInteraction(cb, handle)
+		return nil
+	}
+
+	if form.Get("command") == "" {
+		return fmt.Errorf("neither command nor payload found")
+	}
+
+	sui.handleSlashCommand(slack.SlashCommand{
+		TeamID:      form.Get("team_id"),
+		ChannelID:   form.Get("channel_id"),
+		ChannelName: form.Get("channel_name"),
+		UserID:      form.Get("user_id"),
+		UserName:    form.Get("user_name"),
+		Command:     form.Get("command"),
+		Text:        form.Get("text"),
+		ResponseURL: form.Get("response_url"),
+		TriggerID:   form.Get("trigger_id"),
+		APIAppID:    form.Get("api_app_id"),
+	}, handle)
+	return nil
+}
+
+// handleSlashCommand delivers the slash command as a message with the text
+// after the command as the Body and the command (e.g., '/snowy') as Payload.
+func (sui *SlackUI) handleSlashCommand(cmd slack.SlashCommand, handle func(msg Msg)) {
+	from := slackInteractor(cmd.UserID, cmd.UserName, cmd.ChannelID, cmd.ResponseURL)
+	sui.describe(context.Background(), &from)
+
+	handle(Msg{
+		ID:          cmd.TriggerID,
+		At:          time.Now(),
+		From:        from,
+		Body:        cmd.Text,
+		Payload:     cmd.Command,
+		Channel:     cmd.ChannelID,
+		ChannelType: slackChannelTypeOf(cmd.ChannelID),
+		Raw:         cmd,
+	})
+}
+
+// handleInteraction delivers block actions (e.g., clicks on buttons rendered
+// for Msg.Buttons and Msg.QuickReplies) as messages with the label as Body and
+// the value as Payload. Modal submissions are delivered with an intent tagged
+// with the callback ID of the view and the submitted values as its context.
+func (sui *SlackUI) handleInteraction(cb slack.InteractionCallback, handle func(msg Msg)) {
+	channel := cb.Channel.ID
+	if channel == "" {
+		channel = cb.Container.ChannelID
+	}
+
+	from := slackInteractor(cb.User.ID, cb.User.Name, channel, cb.ResponseURL)
+	sui.describe(context.Background(), &from)
+	if cb.Message.ThreadTimestamp != "" {
+		from.Attribs["slack_ts"] = cb.Message.ThreadTimestamp
+	}
+
+	msg := Msg{
+		At:          time.Now(),
+		From:        from,
+		Channel:     channel,
+		ChannelType: slackChannelTypeOf(channel),
+		Thread:      cb.Message.ThreadTimestamp,
+		Raw:         cb,
+	}
+
+	switch cb.Type {
+	case slack.InteractionTypeBlockActions:
+		for _, action := range cb.ActionCallback.BlockActions {
+			msg.ID = action.ActionTs
+			msg.Body, msg.Payload = action.Text.Text, action.Value
+			if action.SelectedOption.Value != "" {
+				msg.Payload = action.SelectedOption.Value
+				if action.SelectedOption.Text != nil {
+					msg.Body = action.SelectedOption.Text.Text
+				}
+			}
+			handle(msg)
+		}
+
+	case slack.InteractionTypeViewSubmission:
+		values := map[string]interface{}{}
+		if cb.View.State != nil {
+			for _, actions := range cb.View.State.Values {
+				for actionID, action := range actions {
+					values[actionID] = blockActionValue(action)
+				}
+			}
+		}
+
+		msg.ID = cb.View.ID
+		msg.Payload = cb.View.CallbackID
+		msg.Intents = []Intent{{Tag: cb.View.CallbackID, Context: values, Confidence: 1}}
+		handle(msg)
+
+	default:
+		sui.Debugf("unhandled interaction: %s", cb.Type)
+	}
+}
+
+// postResponse posts the message to the response URL of a slash command or
+// an interaction.
+func (sui *SlackUI) postResponse(ctx context.Context, responseURL string, msg Msg) error {
+	res := slackResponse{
+		Text:         msg.Body,
+		Blocks:       slackBlocks(msg),
+		ResponseType: "in_channel",
+	}
+	res.ThreadTS, _ = msg.To.Attribs["slack_ts"].(string)
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, responseURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("response url returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// slackInteractor returns the user for slash commands and interactions. The
+// user ID is used as the channel (i.e., the app DM) when there is no channel
+// (e.g., modal submissions).
+func slackInteractor(id, name, channel, responseURL string) User {
+	if channel == "" {
+		channel = id
+	}
+
+	attribs := map[string]interface{}{"slack_channel": channel}
+	if responseURL != "" {
+		attribs["slack_response_url"] = responseURL
+	}
+	return User{ID: id, Name: name, Attribs: attribs}
+}
+
+// slackChannelTypeOf guesses the type of the channel from its ID.
+func slackChannelTypeOf(channelID string) ChannelType {
+	switch {
+	case strings.HasPrefix(channelID, "D"):
+		return ChannelDirect
+	case strings.HasPrefix(channelID, "G"):
+		return ChannelPrivate
+	default:
+		return ChannelPublic
+	}
+}
+
+func blockActionValue(action slack.BlockAction) interface{} {
+	switch {
+	case action.SelectedOption.Value != "":
+		return action.SelectedOption.Value
+	case len(action.SelectedOptions) > 0:
+		var values []string
+		for _, opt := range action.SelectedOptions {
+			values = append(values, opt.Value)
+		}
+		return values
+	case action.SelectedUser != "":
+		return action.SelectedUser
+	case action.SelectedChannel != "":
+		return action.SelectedChannel
+	case action.SelectedConversation != "":
+		return action.SelectedConversation
+	case action.SelectedDate != "":
+		return action.SelectedDate
+	default:
+		return action.Value
+	}
+}
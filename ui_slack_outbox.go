@@ -0,0 +1,137 @@
+package snowman
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	// slackMaxTextLen is the maximum length of the text sent in a single
+	// message. Slack truncates longer messages and rejects section blocks
+	// longer than this.
+	slackMaxTextLen = 3000
+
+	slackMaxRetries          = 3
+	slackRetryBackoff        = 250 * time.Millisecond
+	defaultSlackPostInterval = time.Second
+)
+
+// slackLane serialises the calls made for a channel and tracks when the next
+// call is allowed.
+type slackLane struct {
+	mu   sync.Mutex
+	next time.Time
+}
+
+// send invokes the call in the outbound queue of the channel. Calls to the
+// same channel are made one at a time and at least PostInterval apart. Rate
+// limited calls are retried after the duration requested by Slack and other
+// transient failures are retried with exponential backoff.
+func (sui *SlackUI) send(ctx context.Context, channel string, call func() error) error {
+	lane := sui.lane(channel)
+	lane.mu.Lock()
+	defer lane.mu.Unlock()
+
+	for attempt := 0; ; attempt++ {
+		if err := sleepUntil(ctx, lane.next); err != nil {
+			return err
+		}
+
+		err := call()
+		lane.next = time.Now().Add(sui.postInterval())
+		if err == nil {
+			return nil
+		} else if attempt >= slackMaxRetries || !isRetryable(err) {
+			return err
+		}
+
+		var rateLimited *slack.RateLimitedError
+		if errors.As(err, &rateLimited) {
+			lane.next = time.Now().Add(rateLimited.RetryAfter)
+		} else {
+			lane.next = time.Now().Add(slackRetryBackoff << attempt)
+		}
+		sui.Warnf("call to channel '%s' failed, retrying at %s: %v", channel, lane.next.Format(time.RFC3339), err)
+	}
+}
+
+func (sui *SlackUI) lane(channel string) *slackLane {
+	sui.mu.Lock()
+	defer sui.mu.Unlock()
+
+	if sui.lanes == nil {
+		sui.lanes = map[string]*slackLane{}
+	}
+
+	lane, found := sui.lanes[channel]
+	if !found {
+		lane = &slackLane{}
+		sui.lanes[channel] = lane
+	}
+	return lane
+}
+
+func (sui *SlackUI) postInterval() time.Duration {
+	if sui.PostInterval < 0 {
+		return 0
+	} else if sui.PostInterval == 0 {
+		return defaultSlackPostInterval
+	}
+	return sui.PostInterval
+}
+
+func isRetryable(err error) bool {
+	var retryable interface{ Retryable() bool }
+	if errors.As(err, &retryable) {
+		return retryable.Retryable()
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func sleepUntil(ctx context.Context, t time.Time) error {
+	wait := time.Until(t)
+	if wait <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// splitText splits the text into chunks of at most max bytes. Chunks are
+// split at line breaks or spaces when possible.
+func splitText(text string, max int) []string {
+	var chunks []string
+	for len(text) > max {
+		cut := max
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+
+		if i := strings.LastIndex(text[:cut], "\n"); i > max/2 {
+			cut = i
+		} else if i := strings.LastIndex(text[:cut], " "); i > max/2 {
+			cut = i
+		}
+
+		chunks = append(chunks, text[:cut])
+		text = strings.TrimLeft(text[cut:], " \n")
+	}
+	return append(chunks, text)
+}
@@ -0,0 +1,174 @@
+package snowman
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// socketEnvelope is the frame received from Slack over Socket Mode.
+type socketEnvelope struct {
+	Type       string          `json:"type"`
+	EnvelopeID string          `json:"envelope_id"`
+	Reason     string          `json:"reason"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+func (sui *SlackUI) listenSocketMode(ctx context.Context, handle func(msg Msg)) error {
+	if sui.AppToken == "" {
+		return errors.New("app token must be set for socket mode")
+	}
+
+	if err := sui.identify(ctx); err != nil {
+		return err
+	}
+
+	attempt := 0
+	for {
+		attempt++
+		sui.Infof("connecting [attempt=%d]...", attempt)
+
+		connected, err := sui.runSocket(ctx, handle)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if connected {
+			attempt = 0
+		} else if attempt >= maxConnectAttempts {
+			return fmt.Errorf("failed to connect even after %d attempts: %w", attempt, err)
+		}
+
+		if err != nil {
+			sui.Warnf("socket mode connection failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Duration(attempt) * time.Second):
+		}
+	}
+}
+
+// runSocket opens a Socket Mode connection and handles the envelopes received
+// until Slack asks to reconnect or the connection fails. Returns true if the
+// connection was established.
+func (sui *SlackUI) runSocket(ctx context.Context, handle func(msg Msg)) (bool, error) {
+	wsURL, err := sui.openSocket(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, wsURL, nil)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-stop:
+		}
+	}()
+
+	connected := false
+	for {
+		var env socketEnvelope
+		if err := conn.ReadJSON(&env); err != nil {
+			return connected, err
+		}
+
+		if env.EnvelopeID != "" {
+			if err := conn.WriteJSON(map[string]string{"envelope_id": env.EnvelopeID}); err != nil {
+				return connected, err
+			}
+		}
+
+		switch env.Type {
+		case "hello":
+			connected = true
+			sui.connected = true
+			sui.Infof("connected as '%s' (ID: %s)", sui.self.Name, sui.self.ID)
+
+		case "disconnect":
+			sui.Infof("slack requested reconnect: %s", env.Reason)
+			return connected, nil
+
+		case "events_api":
+			if sui.invalidateEvent(env.Payload) {
+				continue
+			}
+
+			ev, err := slackevents.ParseEvent(env.Payload, slackevents.OptionNoVerifyToken())
+			if err != nil {
+				sui.Warnf("failed to parse event: %v", err)
+				continue
+			}
+			sui.handleEventsAPI(ev, handle)
+
+		case "slash_commands":
+			var cmd slack.SlashCommand
+			if err := json.Unmarshal(env.Payload, &cmd); err != nil {
+				sui.Warnf("failed to parse slash command: %v", err)
+				continue
+			}
+			sui.handleSlashCommand(cmd, handle)
+
+		case "interactive":
+			var cb slack.InteractionCallback
+			if err := json.Unmarshal(env.Payload, &cb); err != nil {
+				sui.Warnf("failed to parse interaction: %v", err)
+				continue
+			}
+			sui.handleInteraction(cb, handle)
+
+		default:
+			sui.Debugf("unhandled socket mode envelope: %s", env.Type)
+		}
+	}
+}
+
+// openSocket requests a Socket Mode websocket URL using the app token.
+func (sui *SlackUI) openSocket(ctx context.Context) (string, error) {
+	apiURL := sui.APIURL
+	if apiURL == "" {
+		apiURL = slack.APIURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(apiURL, "/")+"/apps.connections.open", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+sui.AppToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var res struct {
+		slack.SlackResponse
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return "", err
+	} else if !res.Ok {
+		return "", res.Err()
+	}
+	return res.URL, nil
+}
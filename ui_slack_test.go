@@ -0,0 +1,531 @@
+package snowman_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/slack-go/slack"
+
+	"github.com/spy16/snowman"
+)
+
+const messageEvent = `{
+	"type": "event_callback",
+	"team_id": "T1",
+	"event": {"type": "message", "channel": "D1", "channel_type": "im", "user": "U1", "text": "hello", "ts": "1.0"}
+}`
+
+func TestSlackUI(t *testing.T) {
+	t.Parallel()
+
+	echo := snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+		if msg.From.ID != "U1" || msg.Channel != "D1" || msg.ChannelType != snowman.ChannelDirect {
+			return fmt.Errorf("unexpected message: %+v", msg)
+		}
+		return di.Say(msg.Context(), "you said "+msg.Body)
+	})
+
+	t.Run("Events", func(t *testing.T) {
+		fake := newFakeSlack(t)
+		ui := &snowman.SlackUI{
+			Mode:          snowman.SlackEvents,
+			Token:         "xoxb-test",
+			SigningSecret: "secret",
+			APIURL:        fake.URL + "/",
+			PostInterval:  -1,
+		}
+		srv := httptest.NewServer(ui)
+		defer srv.Close()
+		runBot(t, ui, echo)
+
+		challenge := `{"type": "url_verification", "challenge": "abc123"}`
+		resp := postSlackEvent(t, srv.URL, "secret", challenge)
+		body := readBody(t, resp)
+		if resp.StatusCode != http.StatusOK || body != "abc123" {
+			t.Errorf("url_verification want 200 with 'abc123', got %d with '%s'", resp.StatusCode, body)
+		}
+
+		resp = postSlackEvent(t, srv.URL, "wrong-secret", messageEvent)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("invalid signature want status 401, got %d", resp.StatusCode)
+		}
+
+		resp = postSlackEvent(t, srv.URL, "secret", messageEvent)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("message want status 200, got %d", resp.StatusCode)
+		}
+		fake.expectPost(t, "D1", "you said hello")
+	})
+
+	t.Run("Cache", func(t *testing.T) {
+		fake := newFakeSlack(t)
+		ui := &snowman.SlackUI{
+			Mode:          snowman.SlackEvents,
+			Token:         "xoxb-test",
+			SigningSecret: "secret",
+			APIURL:        fake.URL + "/",
+			PostInterval:  -1,
+		}
+		srv := httptest.NewServer(ui)
+		defer srv.Close()
+		runBot(t, ui, snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+			u := msg.From
+			return di.Say(msg.Context(), u.Name+"|"+u.DisplayName+"|"+u.Email+"|"+u.TimeZone)
+		}))
+
+		userChange := `{"type": "event_callback", "event": {"type": "user_change", "user": {"id": "U1"}}}`
+		for _, body := range []string{messageEvent, messageEvent, userChange, messageEvent} {
+			resp := postSlackEvent(t, srv.URL, "secret", body)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("want status 200, got %d", resp.StatusCode)
+			}
+			if body != userChange {
+				fake.expectPost(t, "D1", "alice|Alice|alice@example.com|Europe/Berlin")
+			}
+		}
+
+		if n := fake.count("/conversations.info"); n != 1 {
+			t.Errorf("want conversation info fetched once, got %d", n)
+		}
+		if n := fake.count("/users.info"); n != 2 {
+			t.Errorf("want user info fetched twice (before and after change), got %d", n)
+		}
+	})
+
+	t.Run("Ack", func(t *testing.T) {
+		fake := newFakeSlack(t)
+		fake.hold = make(chan struct{})
+		ui := &snowman.SlackUI{
+			Mode:          snowman.SlackEvents,
+			Token:         "xoxb-test",
+			SigningSecret: "secret",
+			APIURL:        fake.URL + "/",
+			PostInterval:  -1,
+		}
+		srv := httptest.NewServer(ui)
+		defer srv.Close()
+		runBot(t, ui, snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+			return di.Say(msg.Context(), "you said "+msg.Body)
+		}))
+
+		var once sync.Once
+		release := func() { once.Do(func() { close(fake.hold) }) }
+		time.AfterFunc(2*time.Second, release)
+
+		// slack gives up after 3 seconds, so requests must be acknowledged
+		// while the user info is still being fetched.
+		slash := url.Values{
+			"command":      {"/snowy"},
+			"text":         {"weather"},
+			"user_id":      {"U1"},
+			"channel_id":   {"D1"},
+			"response_url": {fake.URL + "/response"},
+		}
+		for _, body := range []string{messageEvent, slash.Encode()} {
+			start := time.Now()
+			resp := postSlackEvent(t, srv.URL, "secret", body)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("want status 200, got %d", resp.StatusCode)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("want request acknowledged right away, took %s", elapsed)
+			}
+		}
+
+		release()
+		got := map[string]bool{}
+		for i := 0; i < 2; i++ {
+			select {
+			case post := <-fake.posts:
+				got[post[0]+": "+post[1]] = true
+			case <-time.After(2 * time.Second):
+				t.Fatalf("want 2 replies once user info is fetched, got %v", got)
+			}
+		}
+		if !got["D1: you said hello"] || !got["response: you said weather"] {
+			t.Errorf("want replies to both the event and the command, got %v", got)
+		}
+	})
+
+	t.Run("Outbox", func(t *testing.T) {
+		fake := newFakeSlack(t)
+		fake.rateLimited = 1
+		ui := &snowman.SlackUI{
+			Mode:          snowman.SlackEvents,
+			Token:         "xoxb-test",
+			SigningSecret: "secret",
+			APIURL:        fake.URL + "/",
+			PostInterval:  10 * time.Millisecond,
+		}
+		srv := httptest.NewServer(ui)
+		defer srv.Close()
+
+		long := strings.Repeat("snowman ", 900)
+		runBot(t, ui, snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+			return di.Say(msg.Context(), long)
+		}))
+
+		start := time.Now()
+		resp := postSlackEvent(t, srv.URL, "secret", messageEvent)
+		resp.Body.Close()
+
+		var got string
+		for i := 0; i < 3; i++ {
+			select {
+			case post := <-fake.posts:
+				if len(post[1]) > 3000 {
+					t.Errorf("want parts of at most 3000 bytes, got %d", len(post[1]))
+				}
+				got += post[1] + " "
+			case <-time.After(3 * time.Second):
+				t.Fatalf("want 3 parts, got %d", i)
+			}
+		}
+
+		if elapsed := time.Since(start); elapsed < time.Second {
+			t.Errorf("want retry after 1s as requested by slack, got %s", elapsed)
+		}
+		if strings.Join(strings.Fields(got), " ") != strings.TrimSpace(long) {
+			t.Errorf("parts do not add up to the message")
+		}
+	})
+
+	t.Run("Interactions", func(t *testing.T) {
+		fake := newFakeSlack(t)
+		ui := &snowman.SlackUI{
+			Mode:          snowman.SlackEvents,
+			Token:         "xoxb-test",
+			SigningSecret: "secret",
+			APIURL:        fake.URL + "/",
+			PostInterval:  -1,
+		}
+		srv := httptest.NewServer(ui)
+		defer srv.Close()
+		runBot(t, ui, snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+			return di.Say(msg.Context(), msg.Payload+" "+msg.Body)
+		}))
+
+		slash := url.Values{
+			"command":      {"/snowy"},
+			"text":         {"weather"},
+			"user_id":      {"U1"},
+			"channel_id":   {"D1"},
+			"response_url": {fake.URL + "/response"},
+		}
+		resp := postSlackEvent(t, srv.URL, "secret", slash.Encode())
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("slash command want status 200, got %d", resp.StatusCode)
+		}
+		fake.expectPost(t, "response", "/snowy weather")
+
+		click := fmt.Sprintf(`{
+			"type": "block_actions",
+			"user": {"id": "U1", "name": "alice"},
+			"channel": {"id": "D1"},
+			"response_url": "%s/response",
+			"actions": [{"type": "button", "action_id": "snowman_reply_0", "block_id": "b1", "text": {"type": "plain_text", "text": "Tea"}, "value": "tea"}]
+		}`, fake.URL)
+		resp = postSlackEvent(t, srv.URL, "secret", url.Values{"payload": {click}}.Encode())
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("block action want status 200, got %d", resp.StatusCode)
+		}
+		fake.expectPost(t, "response", "tea Tea")
+	})
+
+	t.Run("SocketMode", func(t *testing.T) {
+		fake := newFakeSlack(t)
+		ui := &snowman.SlackUI{
+			Mode:         snowman.SlackSocketMode,
+			Token:        "xoxb-test",
+			AppToken:     "xapp-test",
+			APIURL:       fake.URL + "/",
+			PostInterval: -1,
+		}
+		stop := startBot(t, &snowman.Bot{UI: ui, Handler: echo})
+
+		fake.expectPost(t, "D1", "you said hello")
+		select {
+		case id := <-fake.acks:
+			if id != "env-1" {
+				t.Errorf("want ack for 'env-1', got '%s'", id)
+			}
+		case <-time.After(2 * time.Second):
+			t.Errorf("envelope was not acknowledged")
+		}
+
+		if err := stop(); err != nil {
+			t.Errorf("Run() want nil error on shutdown, got %v", err)
+		}
+	})
+}
+
+func TestSlackBlocks(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		title string
+		msg   snowman.Msg
+		want  []string
+	}{
+		{
+			title: "Plain",
+			msg:   snowman.Msg{Body: "hello"},
+		},
+		{
+			title: "Markdown",
+			msg:   snowman.Msg{Body: "*hello*", Blocks: []string{"more", " "}, Markdown: true},
+			want:  []string{"section mrkdwn '*hello*'", "section mrkdwn 'more'"},
+		},
+		{
+			title: "Attachments",
+			msg: snowman.Msg{Body: "files", Attachments: []snowman.Attachment{
+				{Type: snowman.AttachmentImage, URL: "http://x/a.png", Title: "Chart"},
+				{Type: snowman.AttachmentImage, URL: "http://x/b.png"},
+				{Type: snowman.AttachmentFile, URL: "http://x/c.pdf", Name: "c.pdf"},
+				{Type: snowman.AttachmentFile, URL: "http://x/d.pdf"},
+			}},
+			want: []string{
+				"section plain_text 'files'",
+				"image http://x/a.png alt='Chart' title='Chart'",
+				"image http://x/b.png alt='image' title=''",
+				"section mrkdwn '<http://x/c.pdf|c.pdf>'",
+				"section mrkdwn '<http://x/d.pdf|http://x/d.pdf>'",
+			},
+		},
+		{
+			title: "Actions",
+			msg: snowman.Msg{
+				Body: "pick",
+				Buttons: []snowman.Button{
+					{Label: "Yes", Payload: "yes", Style: "primary"},
+					{Label: "Docs", URL: "http://x/docs"},
+				},
+				QuickReplies: []snowman.QuickReply{{Label: "No"}},
+			},
+			want: []string{
+				"section plain_text 'pick'",
+				"button snowman_button_0 'Yes' value='yes' url='' style='primary'",
+				"button snowman_button_1 'Docs' value='Docs' url='http://x/docs' style=''",
+				"button snowman_reply_0 'No' value='No' url='' style=''",
+			},
+		},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.title, func(t *testing.T) {
+			var got []string
+			for _, block := range snowman.SlackBlocks(tt.msg) {
+				got = append(got, describeBlock(block)...)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("want blocks:\n%s\ngot:\n%s", strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+// describeBlock summarises the block (or the buttons of an action block) for
+// comparison.
+func describeBlock(block slack.Block) []string {
+	switch b := block.(type) {
+	case *slack.SectionBlock:
+		return []string{fmt.Sprintf("section %s '%s'", b.Text.Type, b.Text.Text)}
+
+	case *slack.ImageBlock:
+		title := ""
+		if b.Title != nil {
+			title = b.Title.Text
+		}
+		return []string{fmt.Sprintf("image %s alt='%s' title='%s'", b.ImageURL, b.AltText, title)}
+
+	case *slack.ActionBlock:
+		var buttons []string
+		for _, el := range b.Elements.ElementSet {
+			btn := el.(*slack.ButtonBlockElement)
+			buttons = append(buttons, fmt.Sprintf("button %s '%s' value='%s' url='%s' style='%s'",
+				btn.ActionID, btn.Text.Text, btn.Value, btn.URL, btn.Style))
+		}
+		return buttons
+
+	default:
+		return []string{fmt.Sprintf("unexpected block %T", block)}
+	}
+}
+
+type fakeSlack struct {
+	*httptest.Server
+	posts chan [2]string
+	acks  chan string
+	calls sync.Map
+
+	// rateLimited is the number of upcoming posts to reject with 429.
+	rateLimited int32
+
+	// hold, if set, holds the users.info responses until it is closed.
+	hold chan struct{}
+}
+
+// newFakeSlack starts a fake Slack API server. The Socket Mode connection
+// delivers a single message event.
+func newFakeSlack(t *testing.T) *fakeSlack {
+	t.Helper()
+
+	fs := &fakeSlack{posts: make(chan [2]string, 10), acks: make(chan string, 10)}
+
+	mux := http.NewServeMux()
+	handle := func(path string, fn http.HandlerFunc) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			n, _ := fs.calls.LoadOrStore(path, new(int32))
+			atomic.AddInt32(n.(*int32), 1)
+			fn(w, r)
+		})
+	}
+	handle("/auth.test", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"ok": true, "user": "snowy", "user_id": "UBOT"}`)
+	})
+	handle("/conversations.info", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"ok": true, "channel": {"id": "D1", "is_im": true}}`)
+	})
+	handle("/users.info", func(w http.ResponseWriter, r *http.Request) {
+		if fs.hold != nil {
+			<-fs.hold
+		}
+		fmt.Fprint(w, `{"ok": true, "user": {"id": "U1", "name": "alice", "tz": "Europe/Berlin",
+			"profile": {"display_name": "Alice", "email": "alice@example.com"}}}`)
+	})
+	handle("/chat.postMessage", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&fs.rateLimited, -1) >= 0 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		_ = r.ParseForm()
+		fs.posts <- [2]string{r.FormValue("channel"), r.FormValue("text")}
+		fmt.Fprint(w, `{"ok": true, "channel": "D1", "ts": "2.0"}`)
+	})
+	handle("/response", func(w http.ResponseWriter, r *http.Request) {
+		var res struct {
+			Text string `json:"text"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&res)
+		fs.posts <- [2]string{"response", res.Text}
+	})
+	handle("/apps.connections.open", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xapp-test" {
+			fmt.Fprint(w, `{"ok": false, "error": "invalid_auth"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"ok": true, "url": "ws%s/socket"}`, strings.TrimPrefix(fs.URL, "http"))
+	})
+	handle("/socket", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		_ = conn.WriteJSON(map[string]interface{}{"type": "hello"})
+		_ = conn.WriteMessage(websocket.TextMessage,
+			[]byte(`{"type": "events_api", "envelope_id": "env-1", "payload": `+messageEvent+`}`))
+
+		for {
+			var ack struct {
+				EnvelopeID string `json:"envelope_id"`
+			}
+			if err := conn.ReadJSON(&ack); err != nil {
+				return
+			}
+			fs.acks <- ack.EnvelopeID
+		}
+	})
+
+	fs.Server = httptest.NewServer(mux)
+	t.Cleanup(fs.Close)
+	return fs
+}
+
+func (fs *fakeSlack) count(path string) int32 {
+	n, found := fs.calls.Load(path)
+	if !found {
+		return 0
+	}
+	return atomic.LoadInt32(n.(*int32))
+}
+
+func (fs *fakeSlack) expectPost(t *testing.T, channel, text string) {
+	t.Helper()
+
+	select {
+	case got := <-fs.posts:
+		if got[0] != channel || got[1] != text {
+			t.Errorf("want '%s' posted to '%s', got '%s' posted to '%s'", text, channel, got[1], got[0])
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("nothing posted to '%s'", channel)
+	}
+}
+
+// postSlackEvent posts the JSON event or the form signed using the secret,
+// retrying while the UI is not listening yet.
+func postSlackEvent(t *testing.T, url, secret, body string) *http.Response {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte("v0:" + ts + ":" + body))
+
+		req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if !strings.HasPrefix(body, "{") {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		req.Header.Set("X-Slack-Request-Timestamp", ts)
+		req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST failed: %v", err)
+		}
+
+		if resp.StatusCode != http.StatusServiceUnavailable || time.Now().After(deadline) {
+			return resp
+		}
+		resp.Body.Close()
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read body: %v", err)
+	}
+	return string(data)
+}
@@ -0,0 +1,268 @@
+package snowman
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var _ UI = (*SocketUI)(nil)
+
+const (
+	maxSocketLineSize  = 1 << 20
+	socketWriteTimeout = 10 * time.Second
+)
+
+// SocketUI implements a line based UI over a TCP or Unix socket. Every
+// connection is a separate user. By default, each line received is a message
+// body and replies are written back as plain text terminated by a newline
+// (rendered the same way as ConsoleUI). If JSON is set, every line received
+// must be a JSON encoded Msg and replies are written as JSON lines instead.
+type SocketUI struct {
+	Logger
+
+	// Network must be "tcp" (default), "tcp4", "tcp6" or "unix".
+	Network string
+	Addr    string
+	JSON    bool
+
+	mu       sync.Mutex
+	conns    map[string]*socketConn
+	prefix   string
+	lastID   int
+	closing  bool
+	inflight *inflight
+}
+
+type socketConn struct {
+	id   string
+	conn net.Conn
+
+	mu      sync.Mutex
+	choices []QuickReply
+	lastID  int
+}
+
+// Say writes the message to the connection of the msg.To user.
+func (sui *SocketUI) Say(_ context.Context, msg Msg) error {
+	sui.mu.Lock()
+	sc, found := sui.conns[msg.To.ID]
+	sui.mu.Unlock()
+
+	if !found {
+		return fmt.Errorf("no connection for user '%s'", msg.To.ID)
+	}
+	return sc.write(msg, sui.JSON)
+}
+
+// Listen accepts connections on the socket and blocks until the ctx is
+// cancelled. The connections open when it returns are closed once the bot is
+// done with the messages already received, so that the replies to them are
+// still delivered.
+func (sui *SocketUI) Listen(ctx context.Context, handle func(msg Msg)) error {
+	if sui.Logger == nil {
+		sui.Logger = NoOpLogger{}
+	}
+
+	network := sui.Network
+	if network == "" {
+		network = "tcp"
+	}
+
+	ln, err := net.Listen(network, sui.Addr)
+	if err != nil {
+		return err
+	}
+	sui.Infof("accepting %s connections on '%s'", network, ln.Addr())
+
+	// connection IDs are the dialogue IDs of the users. the random prefix
+	// keeps new connections from inheriting the dialogues (and jobs) of the
+	// connections before a restart.
+	var nonce [4]byte
+	_, _ = rand.Read(nonce[:])
+
+	sui.mu.Lock()
+	sui.conns = map[string]*socketConn{}
+	sui.prefix = "socket-" + hex.EncodeToString(nonce[:]) + "-"
+	sui.lastID = 0
+	sui.closing = false
+	sui.inflight = &inflight{}
+	sui.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		_ = ln.Close()
+	}()
+
+	var wg sync.WaitGroup
+	defer func() {
+		sui.stopReading()
+		wg.Wait()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Temporary() {
+				sui.Warnf("accept failed, retrying: %v", err)
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+
+		sc := sui.track(conn)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer sui.untrack(sc)
+			sui.serve(sc, handle)
+		}()
+	}
+}
+
+func (sui *SocketUI) serve(sc *socketConn, handle func(msg Msg)) {
+	sui.mu.Lock()
+	inflight := sui.inflight
+	sui.mu.Unlock()
+
+	sui.Debugf("user '%s' connected from '%s'", sc.id, sc.conn.RemoteAddr())
+
+	scanner := bufio.NewScanner(sc.conn)
+	scanner.Buffer(make([]byte, 0, 4096), maxSocketLineSize)
+	for scanner.Scan() {
+		msg, err := sc.toMsg(scanner.Bytes(), sui.JSON)
+		if err != nil {
+			sui.Warnf("invalid message from user '%s': %v", sc.id, err)
+			continue
+		}
+
+		if !inflight.track(&msg) {
+			return
+		}
+		handle(msg)
+	}
+
+	if err := scanner.Err(); err != nil {
+		sui.Debugf("read failed for user '%s': %v", sc.id, err)
+	}
+	sui.Debugf("user '%s' disconnected", sc.id)
+}
+
+func (sui *SocketUI) track(conn net.Conn) *socketConn {
+	sui.mu.Lock()
+	defer sui.mu.Unlock()
+
+	sui.lastID++
+	sc := &socketConn{
+		id:   sui.prefix + strconv.Itoa(sui.lastID),
+		conn: conn,
+	}
+	sui.conns[sc.id] = sc
+	return sc
+}
+
+func (sui *SocketUI) untrack(sc *socketConn) {
+	sui.mu.Lock()
+	defer sui.mu.Unlock()
+
+	// once Listen returns, the connection is closed by closeAll instead.
+	if sui.closing {
+		return
+	}
+	delete(sui.conns, sc.id)
+	_ = sc.conn.Close()
+}
+
+// stopReading stops reading messages from the open connections. They are
+// closed once the bot is done with the messages already received.
+func (sui *SocketUI) stopReading() {
+	sui.mu.Lock()
+	sui.closing = true
+	for _, sc := range sui.conns {
+		_ = sc.conn.SetReadDeadline(time.Now())
+	}
+	inflight := sui.inflight
+	sui.mu.Unlock()
+
+	inflight.closeThen(sui.closeAll)
+}
+
+func (sui *SocketUI) closeAll() {
+	sui.mu.Lock()
+	defer sui.mu.Unlock()
+
+	for _, sc := range sui.conns {
+		_ = sc.conn.Close()
+	}
+	sui.conns = nil
+}
+
+// toMsg creates a message from the line received. In text mode, the line may
+// also be the number of one of the choices offered in the last message.
+func (sc *socketConn) toMsg(line []byte, asJSON bool) (Msg, error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	var msg Msg
+	if asJSON {
+		if err := json.Unmarshal(line, &msg); err != nil {
+			return Msg{}, err
+		}
+	} else {
+		msg.Body = string(line)
+		pickChoice(&msg, sc.choices)
+		sc.choices = nil
+	}
+
+	sc.lastID++
+	if msg.ID == "" {
+		msg.ID = sc.id + "-" + strconv.Itoa(sc.lastID)
+	}
+	if msg.At.IsZero() {
+		msg.At = time.Now()
+	}
+	msg.From.ID = sc.id
+	msg.From.Attribs = cloneMerge(msg.From.Attribs, map[string]interface{}{
+		"remote_addr": sc.conn.RemoteAddr().String(),
+	})
+	msg.Channel = sc.id
+	msg.ChannelType = ChannelDirect
+	return msg, nil
+}
+
+func (sc *socketConn) write(msg Msg, asJSON bool) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	var data []byte
+	if asJSON {
+		var err error
+		if data, err = json.Marshal(msg); err != nil {
+			return err
+		}
+	} else {
+		text, choices := renderText(msg)
+		if len(choices) > 0 {
+			sc.choices = choices
+		}
+		data = []byte(text)
+	}
+
+	_ = sc.conn.SetWriteDeadline(time.Now().Add(socketWriteTimeout))
+	_, err := sc.conn.Write(append(data, '\n'))
+	return err
+}
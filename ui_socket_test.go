@@ -0,0 +1,171 @@
+package snowman_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spy16/snowman"
+)
+
+func TestSocketUI(t *testing.T) {
+	t.Parallel()
+
+	echo := snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+		if msg.Body == "menu" {
+			return di.Send(msg.Context(), snowman.Msg{
+				Body:         "pick one",
+				QuickReplies: []snowman.QuickReply{{Label: "Tea", Payload: "tea"}, {Label: "Coffee", Payload: "coffee"}},
+			})
+		}
+		return di.Say(msg.Context(), msg.From.ID+": "+msg.Body+" "+msg.Payload)
+	})
+
+	t.Run("Text", func(t *testing.T) {
+		addr := filepath.Join(t.TempDir(), "bot.sock")
+		runBot(t, &snowman.SocketUI{Network: "unix", Addr: addr}, echo)
+
+		alice := dialSocket(t, addr)
+		bob := dialSocket(t, addr)
+
+		alice.send("menu")
+		if got := alice.read(); got != "pick one" {
+			t.Fatalf("want 'pick one', got '%s'", got)
+		}
+		alice.read()
+		alice.read()
+
+		alice.send("2")
+		bob.send("hi")
+		aliceID, got := splitReply(t, alice.read())
+		if got != "Coffee coffee" {
+			t.Errorf("alice want 'Coffee coffee', got '%s'", got)
+		}
+		bobID, got := splitReply(t, bob.read())
+		if got != "hi " {
+			t.Errorf("bob want 'hi ', got '%s'", got)
+		}
+		if aliceID == bobID {
+			t.Errorf("want a separate user per connection, got '%s' for both", aliceID)
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		addr := filepath.Join(t.TempDir(), "bot.sock")
+		runBot(t, &snowman.SocketUI{Network: "unix", Addr: addr, JSON: true}, echo)
+
+		client := dialSocket(t, addr)
+		client.send(`{"body": "hello", "payload": "there"}`)
+
+		var got snowman.Msg
+		if err := json.Unmarshal([]byte(client.read()), &got); err != nil {
+			t.Fatalf("failed to decode reply: %v", err)
+		}
+		if got.Body != got.To.ID+": hello there" {
+			t.Errorf("want reply '%s: hello there', got %+v", got.To.ID, got)
+		}
+	})
+
+	t.Run("Restart", func(t *testing.T) {
+		// connections after a restart must not take over the dialogues (or
+		// the jobs) of the connections before it.
+		ids := map[string]bool{}
+		for i := 0; i < 2; i++ {
+			addr := filepath.Join(t.TempDir(), "bot.sock")
+			ui := &snowman.SocketUI{Network: "unix", Addr: addr}
+			stop := startBot(t, &snowman.Bot{UI: ui, Handler: echo})
+
+			client := dialSocket(t, addr)
+			client.send("hi")
+			id, _ := splitReply(t, client.read())
+			if ids[id] {
+				t.Errorf("want a new connection ID after restart, got '%s' again", id)
+			}
+			ids[id] = true
+			_ = stop()
+		}
+	})
+
+	t.Run("Drain", func(t *testing.T) {
+		addr := filepath.Join(t.TempDir(), "bot.sock")
+
+		started := make(chan struct{}, 1)
+		stop := startBot(t, &snowman.Bot{
+			UI: &snowman.SocketUI{Network: "unix", Addr: addr},
+			Handler: snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+				started <- struct{}{}
+				time.Sleep(50 * time.Millisecond)
+				return di.Say(msg.Context(), "late reply")
+			}),
+		})
+
+		client := dialSocket(t, addr)
+		client.send("hi")
+		waitN(t, started, 1)
+
+		// the reply is produced while the bot drains, after Listen returned.
+		if err := stop(); err != nil {
+			t.Fatalf("Run() unexpected error: %v", err)
+		}
+		if got := client.read(); got != "late reply" {
+			t.Errorf("want 'late reply', got '%s'", got)
+		}
+	})
+}
+
+type socketClient struct {
+	t       *testing.T
+	conn    net.Conn
+	scanner *bufio.Scanner
+}
+
+// dialSocket connects to the unix socket, retrying while the UI is not
+// listening yet.
+func dialSocket(t *testing.T, addr string) *socketClient {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		conn, err := net.Dial("unix", addr)
+		if err == nil {
+			t.Cleanup(func() { conn.Close() })
+			return &socketClient{t: t, conn: conn, scanner: bufio.NewScanner(conn)}
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("dial failed: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (sc *socketClient) send(line string) {
+	sc.t.Helper()
+	if _, err := sc.conn.Write([]byte(line + "\n")); err != nil {
+		sc.t.Fatalf("write failed: %v", err)
+	}
+}
+
+func (sc *socketClient) read() string {
+	sc.t.Helper()
+	_ = sc.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if !sc.scanner.Scan() {
+		sc.t.Fatalf("read failed: %v", sc.scanner.Err())
+	}
+	return sc.scanner.Text()
+}
+
+// splitReply splits the echo reply into the ID of the user and the rest.
+func splitReply(t *testing.T, reply string) (string, string) {
+	t.Helper()
+
+	i := strings.Index(reply, ": ")
+	if i < 0 || !strings.HasPrefix(reply, "socket-") {
+		t.Fatalf("want reply of the form 'socket-...: ...', got '%s'", reply)
+	}
+	return reply[:i], reply[i+2:]
+}
@@ -0,0 +1,475 @@
+package snowman
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	_ UI      = (*TelegramUI)(nil)
+	_ Typer   = (*TelegramUI)(nil)
+	_ Poster  = (*TelegramUI)(nil)
+	_ Updater = (*TelegramUI)(nil)
+)
+
+const (
+	defaultTelegramURL         = "https://api.telegram.org"
+	defaultTelegramPollTimeout = 30 * time.Second
+	telegramMaxTextLen         = 4096
+)
+
+// TelegramUI implements a Telegram bot UI using the Bot API. Updates are
+// received by long-polling 'getUpdates'. Every message is delivered with the
+// chat ID as the Channel, so PerChannel dialogue keys map every chat to a
+// dialogue. Replies are always sent to the chat the message was received in.
+// Buttons and quick replies are rendered as inline keyboards and clicks on
+// them are delivered as messages with the label as Body and the callback data
+// as Payload.
+type TelegramUI struct {
+	Logger
+
+	Token string
+
+	// BaseURL of the Bot API. Defaults to 'https://api.telegram.org'.
+	BaseURL string
+
+	// PollTimeout is the long-polling timeout for 'getUpdates'. Defaults to
+	// 30 seconds.
+	PollTimeout time.Duration
+	Client      *http.Client
+
+	self telegramUser
+}
+
+type telegramUser struct {
+	ID        int64  `json:"id"`
+	IsBot     bool   `json:"is_bot"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Username  string `json:"username"`
+}
+
+type telegramChat struct {
+	ID    int64  `json:"id"`
+	Type  string `json:"type"`
+	Title string `json:"title"`
+}
+
+type telegramMessage struct {
+	MessageID   int64             `json:"message_id"`
+	ThreadID    int64             `json:"message_thread_id"`
+	From        *telegramUser     `json:"from"`
+	Chat        telegramChat      `json:"chat"`
+	Date        int64             `json:"date"`
+	Text        string            `json:"text"`
+	Caption     string            `json:"caption"`
+	ReplyMarkup *telegramKeyboard `json:"reply_markup"`
+}
+
+type telegramCallbackQuery struct {
+	ID      string           `json:"id"`
+	From    telegramUser     `json:"from"`
+	Message *telegramMessage `json:"message"`
+	Data    string           `json:"data"`
+}
+
+type telegramUpdate struct {
+	UpdateID      int64                  `json:"update_id"`
+	Message       *telegramMessage       `json:"message"`
+	CallbackQuery *telegramCallbackQuery `json:"callback_query"`
+}
+
+type telegramKeyboard struct {
+	InlineKeyboard [][]telegramButton `json:"inline_keyboard"`
+}
+
+type telegramButton struct {
+	Text         string `json:"text"`
+	URL          string `json:"url,omitempty"`
+	CallbackData string `json:"callback_data,omitempty"`
+}
+
+// telegramError is the error returned by the Bot API.
+type telegramError struct {
+	Code        int
+	Description string
+	RetryAfter  int
+}
+
+func (err telegramError) Error() string {
+	return fmt.Sprintf("telegram: %s (code=%d)", err.Description, err.Code)
+}
+
+// Say sends the message to the chat of the msg.To user.
+func (tui *TelegramUI) Say(ctx context.Context, msg Msg) error {
+	_, err := tui.Post(ctx, msg)
+	return err
+}
+
+// Post sends the message like Say and returns the ID of the (last) message
+// sent which can be used to Update it. Attachments are sent as separate
+// photo/document messages before the text.
+func (tui *TelegramUI) Post(ctx context.Context, msg Msg) (string, error) {
+	chatID, ok := msg.To.Attribs["telegram_chat"].(string)
+	if !ok {
+		return "", errors.New("telegram_chat attrib missing")
+	}
+
+	var sent telegramMessage
+	for _, att := range msg.Attachments {
+		method, field := "sendDocument", "document"
+		if att.Type == AttachmentImage {
+			method, field = "sendPhoto", "photo"
+		}
+
+		req := map[string]interface{}{"chat_id": chatID, field: att.URL}
+		if caption := payloadOrLabel(att.Title, att.Name); caption != "" {
+			req["caption"] = caption
+		}
+		if err := tui.call(ctx, method, req, &sent); err != nil {
+			return "", err
+		}
+	}
+
+	parts := splitText(telegramText(msg), telegramMaxTextLen)
+	for i, text := range parts {
+		if text == "" && len(msg.Attachments) > 0 {
+			continue
+		}
+
+		req := tui.textRequest(chatID, text, msg)
+		if i == len(parts)-1 {
+			if kb := telegramInlineKeyboard(msg); kb != nil {
+				req["reply_markup"] = kb
+			}
+		}
+		if thread, ok := msg.To.Attribs["telegram_thread"].(string); ok {
+			req["message_thread_id"], _ = strconv.ParseInt(thread, 10, 64)
+		}
+
+		if err := tui.call(ctx, "sendMessage", req, &sent); err != nil {
+			return "", err
+		}
+	}
+
+	return strconv.FormatInt(sent.MessageID, 10), nil
+}
+
+// Update replaces the text and the inline keyboard of the message.
+func (tui *TelegramUI) Update(ctx context.Context, id string, msg Msg) error {
+	chatID, ok := msg.To.Attribs["telegram_chat"].(string)
+	if !ok {
+		return errors.New("telegram_chat attrib missing")
+	}
+
+	messageID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid message id '%s'", id)
+	}
+
+	req := tui.textRequest(chatID, telegramText(msg), msg)
+	req["message_id"] = messageID
+	if kb := telegramInlineKeyboard(msg); kb != nil {
+		req["reply_markup"] = kb
+	}
+	return tui.call(ctx, "editMessageText", req, nil)
+}
+
+// Typing shows the typing indicator in the chat of the msg.To user.
+func (tui *TelegramUI) Typing(ctx context.Context, msg Msg) error {
+	chatID, ok := msg.To.Attribs["telegram_chat"].(string)
+	if !ok {
+		return errors.New("telegram_chat attrib missing")
+	}
+
+	return tui.call(ctx, "sendChatAction", map[string]interface{}{"chat_id": chatID, "action": "typing"}, nil)
+}
+
+// Listen long-polls the Bot API for updates and blocks until the ctx is
+// cancelled or the token is rejected.
+func (tui *TelegramUI) Listen(ctx context.Context, handle func(msg Msg)) error {
+	if tui.Logger == nil {
+		tui.Logger = NoOpLogger{}
+	}
+
+	if err := tui.retry(ctx, "getMe", nil, &tui.self); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("authentication error: %w", err)
+	}
+	tui.Infof("connected as '%s' (ID: %d)", tui.self.Username, tui.self.ID)
+
+	timeout := tui.PollTimeout
+	if timeout <= 0 {
+		timeout = defaultTelegramPollTimeout
+	}
+
+	var offset int64
+	for {
+		req := map[string]interface{}{
+			"offset":          offset,
+			"timeout":         int(timeout / time.Second),
+			"allowed_updates": []string{"message", "callback_query"},
+		}
+
+		var updates []telegramUpdate
+		if err := tui.retry(ctx, "getUpdates", req, &updates); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		for _, update := range updates {
+			offset = update.UpdateID + 1
+			tui.handleUpdate(ctx, update, handle)
+		}
+	}
+}
+
+// retry invokes the Bot API method until it succeeds, the ctx is cancelled or
+// the token is rejected. Other failures (e.g., network errors) are retried
+// with backoff.
+func (tui *TelegramUI) retry(ctx context.Context, method string, params interface{}, v interface{}) error {
+	failures := 0
+	for {
+		err := tui.call(ctx, method, params, v)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+
+		var tgErr telegramError
+		if errors.As(err, &tgErr) && (tgErr.Code == http.StatusUnauthorized || tgErr.Code == http.StatusNotFound) {
+			return err
+		}
+
+		if failures < maxConnectAttempts {
+			failures++
+		}
+		wait := time.Duration(failures) * time.Second
+		if tgErr.RetryAfter > 0 {
+			wait = time.Duration(tgErr.RetryAfter) * time.Second
+		}
+		tui.Warnf("%s failed, retrying in %s: %v", method, wait, err)
+		if err := sleepUntil(ctx, time.Now().Add(wait)); err != nil {
+			return err
+		}
+	}
+}
+
+func (tui *TelegramUI) handleUpdate(ctx context.Context, update telegramUpdate, handle func(msg Msg)) {
+	switch {
+	case update.Message != nil && update.Message.From != nil:
+		tm := update.Message
+		if tm.From.ID == tui.self.ID {
+			return
+		}
+
+		text := tm.Text
+		if text == "" {
+			text = tm.Caption
+		}
+
+		msg := tui.toMsg(*tm.From, tm)
+		msg.Body = tui.stripAddress(text)
+		handle(msg)
+
+	case update.CallbackQuery != nil && update.CallbackQuery.Message != nil:
+		cq := update.CallbackQuery
+		if err := tui.call(ctx, "answerCallbackQuery", map[string]interface{}{"callback_query_id": cq.ID}, nil); err != nil {
+			tui.Warnf("failed to answer callback query: %v", err)
+		}
+
+		msg := tui.toMsg(cq.From, cq.Message)
+		msg.ID = cq.ID
+		msg.Body, msg.Payload = cq.Data, cq.Data
+		if kb := cq.Message.ReplyMarkup; kb != nil {
+			for _, row := range kb.InlineKeyboard {
+				for _, btn := range row {
+					if btn.CallbackData == cq.Data {
+						msg.Body = btn.Text
+					}
+				}
+			}
+		}
+		handle(msg)
+
+	default:
+		tui.Debugf("unhandled update: %d", update.UpdateID)
+	}
+}
+
+func (tui *TelegramUI) toMsg(from telegramUser, tm *telegramMessage) Msg {
+	chatID := strconv.FormatInt(tm.Chat.ID, 10)
+	user := User{
+		ID:          strconv.FormatInt(from.ID, 10),
+		Name:        from.Username,
+		DisplayName: strings.TrimSpace(from.FirstName + " " + from.LastName),
+		Attribs:     map[string]interface{}{"telegram_chat": chatID},
+	}
+
+	var thread string
+	if tm.ThreadID != 0 {
+		thread = strconv.FormatInt(tm.ThreadID, 10)
+		user.Attribs["telegram_thread"] = thread
+	}
+
+	return Msg{
+		ID:          strconv.FormatInt(tm.MessageID, 10),
+		At:          time.Unix(tm.Date, 0),
+		From:        user,
+		Channel:     chatID,
+		ChannelType: telegramChannelType(tm.Chat.Type),
+		Thread:      thread,
+		Raw:         tm,
+	}
+}
+
+// stripAddress removes the bot username from commands (e.g., '/start@bot')
+// and from the beginning of messages addressed to the bot in groups.
+func (tui *TelegramUI) stripAddress(text string) string {
+	if tui.self.Username == "" {
+		return text
+	}
+
+	mention := "@" + tui.self.Username
+	if strings.HasPrefix(text, "/") {
+		return strings.Replace(text, mention, "", 1)
+	}
+	return strings.TrimSpace(strings.TrimPrefix(text, mention))
+}
+
+func (tui *TelegramUI) textRequest(chatID, text string, msg Msg) map[string]interface{} {
+	req := map[string]interface{}{"chat_id": chatID, "text": text}
+	if msg.Markdown {
+		req["parse_mode"] = "Markdown"
+	}
+	return req
+}
+
+// call invokes the Bot API method and decodes the result into v (if not nil).
+func (tui *TelegramUI) call(ctx context.Context, method string, params interface{}, v interface{}) error {
+	baseURL := tui.BaseURL
+	if baseURL == "" {
+		baseURL = defaultTelegramURL
+	}
+
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	endpoint := strings.TrimSuffix(baseURL, "/") + "/bot" + tui.Token + "/" + method
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return telegramCallError(method, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := tui.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return telegramCallError(method, err)
+	}
+	defer resp.Body.Close()
+
+	var res struct {
+		OK          bool            `json:"ok"`
+		Result      json.RawMessage `json:"result"`
+		ErrorCode   int             `json:"error_code"`
+		Description string          `json:"description"`
+		Parameters  struct {
+			RetryAfter int `json:"retry_after"`
+		} `json:"parameters"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return fmt.Errorf("telegram: failed to decode response (status=%d): %w", resp.StatusCode, err)
+	}
+
+	if !res.OK {
+		code := res.ErrorCode
+		if code == 0 {
+			code = resp.StatusCode
+		}
+		return telegramError{Code: code, Description: res.Description, RetryAfter: res.Parameters.RetryAfter}
+	}
+
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(res.Result, v)
+}
+
+// telegramCallError returns the error without the request URL since the URL
+// contains the token.
+func telegramCallError(method string, err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	return fmt.Errorf("telegram: %s failed: %w", method, err)
+}
+
+// telegramText returns the text of the message with the blocks appended.
+func telegramText(msg Msg) string {
+	return strings.Join(append([]string{msg.Body}, msg.Blocks...), "\n\n")
+}
+
+// telegramInlineKeyboard renders the buttons and the quick replies of the
+// message as an inline keyboard with a row each. Returns nil if there are
+// none.
+func telegramInlineKeyboard(msg Msg) *telegramKeyboard {
+	var kb telegramKeyboard
+
+	var row []telegramButton
+	for _, btn := range msg.Buttons {
+		if btn.URL != "" {
+			row = append(row, telegramButton{Text: btn.Label, URL: btn.URL})
+			continue
+		}
+		row = append(row, telegramButton{Text: btn.Label, CallbackData: payloadOrLabel(btn.Payload, btn.Label)})
+	}
+	if len(row) > 0 {
+		kb.InlineKeyboard = append(kb.InlineKeyboard, row)
+	}
+
+	row = nil
+	for _, qr := range msg.QuickReplies {
+		row = append(row, telegramButton{Text: qr.Label, CallbackData: payloadOrLabel(qr.Payload, qr.Label)})
+	}
+	if len(row) > 0 {
+		kb.InlineKeyboard = append(kb.InlineKeyboard, row)
+	}
+
+	if len(kb.InlineKeyboard) == 0 {
+		return nil
+	}
+	return &kb
+}
+
+func telegramChannelType(chatType string) ChannelType {
+	switch chatType {
+	case "private":
+		return ChannelDirect
+	case "group", "supergroup":
+		return ChannelGroup
+	default:
+		return ChannelPublic
+	}
+}
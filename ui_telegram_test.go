@@ -0,0 +1,130 @@
+package snowman_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spy16/snowman"
+)
+
+func TestTelegramUI(t *testing.T) {
+	t.Parallel()
+
+	updates := make(chan string, 10)
+	sent := make(chan map[string]interface{}, 10)
+	answered := make(chan string, 10)
+
+	// the first getMe fails and must be retried instead of failing Listen.
+	var getMeCalls int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/bottest-token/getMe", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&getMeCalls, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			fmt.Fprint(w, `{"ok": false, "error_code": 502, "description": "Bad Gateway"}`)
+			return
+		}
+		fmt.Fprint(w, `{"ok": true, "result": {"id": 99, "is_bot": true, "username": "snowy_bot"}}`)
+	})
+	mux.HandleFunc("/bottest-token/getUpdates", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case update := <-updates:
+			fmt.Fprintf(w, `{"ok": true, "result": [%s]}`, update)
+		case <-time.After(50 * time.Millisecond):
+			fmt.Fprint(w, `{"ok": true, "result": []}`)
+		case <-r.Context().Done():
+		}
+	})
+	mux.HandleFunc("/bottest-token/sendMessage", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		sent <- req
+		fmt.Fprint(w, `{"ok": true, "result": {"message_id": 7, "chat": {"id": 42, "type": "private"}}}`)
+	})
+	mux.HandleFunc("/bottest-token/answerCallbackQuery", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		answered <- req["callback_query_id"]
+		fmt.Fprint(w, `{"ok": true, "result": true}`)
+	})
+	fake := httptest.NewServer(mux)
+	defer fake.Close()
+
+	ui := &snowman.TelegramUI{Token: "test-token", BaseURL: fake.URL}
+	runBot(t, ui, snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+		if msg.Payload != "" {
+			return di.Say(msg.Context(), "picked "+msg.Body+" ("+msg.Payload+")")
+		}
+		return di.Send(msg.Context(), snowman.Msg{
+			Body:         msg.From.DisplayName + " said " + msg.Body,
+			QuickReplies: []snowman.QuickReply{{Label: "Tea", Payload: "tea"}},
+		})
+	}))
+
+	expectSent := func(want string) map[string]interface{} {
+		t.Helper()
+		select {
+		case req := <-sent:
+			if req["chat_id"] != "42" || req["text"] != want {
+				t.Errorf("want '%s' sent to chat 42, got %v", want, req)
+			}
+			return req
+		case <-time.After(2 * time.Second):
+			t.Fatalf("nothing sent, want '%s'", want)
+			return nil
+		}
+	}
+
+	updates <- `{"update_id": 1, "message": {"message_id": 5, "date": 1600000000, "text": "hello",
+		"from": {"id": 1, "first_name": "Alice", "username": "alice"}, "chat": {"id": 42, "type": "private"}}}`
+	req := expectSent("Alice said hello")
+
+	keyboard, _ := json.Marshal(req["reply_markup"])
+	if string(keyboard) != `{"inline_keyboard":[[{"callback_data":"tea","text":"Tea"}]]}` {
+		t.Errorf("want inline keyboard with 'Tea', got %s", keyboard)
+	}
+
+	updates <- `{"update_id": 2, "callback_query": {"id": "cb1", "data": "tea", "from": {"id": 1, "first_name": "Alice"},
+		"message": {"message_id": 7, "chat": {"id": 42, "type": "private"}, "reply_markup": ` + string(keyboard) + `}}}`
+	expectSent("picked Tea (tea)")
+
+	select {
+	case id := <-answered:
+		if id != "cb1" {
+			t.Errorf("want callback query 'cb1' answered, got '%s'", id)
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("callback query was not answered")
+	}
+}
+
+func TestTelegramUI_ErrorHidesToken(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	baseURL := "http://" + ln.Addr().String()
+	ln.Close()
+
+	ui := &snowman.TelegramUI{Token: "secret-token", BaseURL: baseURL}
+	err = ui.Say(context.Background(), snowman.Msg{
+		Body: "hi",
+		To:   snowman.User{ID: "1", Attribs: map[string]interface{}{"telegram_chat": "42"}},
+	})
+	if err == nil {
+		t.Fatalf("Say() want error when the api is unreachable")
+	}
+
+	if strings.Contains(err.Error(), "secret-token") || !strings.Contains(err.Error(), "sendMessage") {
+		t.Errorf("Say() want error naming the method without the token, got '%v'", err)
+	}
+}
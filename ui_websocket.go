@@ -0,0 +1,290 @@
+package snowman
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"html/template"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+var (
+	_ UI           = (*WebSocketUI)(nil)
+	_ http.Handler = (*WebSocketUI)(nil)
+)
+
+const (
+	wsSessionCookie = "snowman_session"
+	wsWriteTimeout  = 10 * time.Second
+)
+
+// WebSocketUI implements a browser based chat UI. It serves a small chat page
+// at '/' and a websocket endpoint at '/ws'. Every browser session (tracked
+// using a cookie) is a separate user and gets its own dialogue. Messages said
+// to a user are pushed to all the sockets (e.g., tabs) of the session.
+//
+// If Addr is set, Listen starts an HTTP server on it. Otherwise the UI can be
+// mounted on an existing server as an http.Handler while Listen is running.
+type WebSocketUI struct {
+	Logger
+
+	Addr  string
+	Title string
+
+	// CheckOrigin validates the origin of websocket requests. If nil, only
+	// same-origin requests are allowed.
+	CheckOrigin func(r *http.Request) bool
+
+	mu       sync.RWMutex
+	receive  func(msg Msg)
+	sessions map[string]map[*wsConn]struct{}
+	inflight *inflight
+}
+
+type wsConn struct {
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+// wsIncoming is the message sent by the chat page.
+type wsIncoming struct {
+	Body    string `json:"body"`
+	Payload string `json:"payload,omitempty"`
+}
+
+// Say pushes the message to all the sockets of the session identified by the
+// msg.To user.
+func (wui *WebSocketUI) Say(_ context.Context, msg Msg) error {
+	wui.mu.RLock()
+	conns := make([]*wsConn, 0, len(wui.sessions[msg.To.ID]))
+	for c := range wui.sessions[msg.To.ID] {
+		conns = append(conns, c)
+	}
+	wui.mu.RUnlock()
+
+	if len(conns) == 0 {
+		return errors.New("no active sockets for the user")
+	}
+
+	var lastErr error
+	sent := 0
+	for _, c := range conns {
+		if err := c.write(msg); err != nil {
+			lastErr = err
+			continue
+		}
+		sent++
+	}
+
+	if sent == 0 {
+		return lastErr
+	}
+	return nil
+}
+
+// Listen makes the UI ready to accept connections and blocks until the ctx
+// is cancelled. The sockets open when it returns are closed once the bot is
+// done with the messages already received, so that the replies to them are
+// still delivered.
+func (wui *WebSocketUI) Listen(ctx context.Context, handle func(msg Msg)) error {
+	if wui.Logger == nil {
+		wui.Logger = NoOpLogger{}
+	}
+
+	wui.mu.Lock()
+	wui.receive = handle
+	wui.sessions = map[string]map[*wsConn]struct{}{}
+	wui.inflight = &inflight{}
+	wui.mu.Unlock()
+
+	defer wui.stopReading()
+
+	if wui.Addr != "" {
+		wui.Infof("serving web chat on '%s'", wui.Addr)
+	}
+	return serveUntilDone(ctx, wui.Addr, wui)
+}
+
+// ServeHTTP serves the chat page and the websocket endpoint.
+func (wui *WebSocketUI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/":
+		wui.servePage(w, r)
+
+	case "/ws":
+		wui.serveSocket(w, r)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (wui *WebSocketUI) servePage(w http.ResponseWriter, r *http.Request) {
+	if _, err := r.Cookie(wsSessionCookie); err != nil {
+		http.SetCookie(w, &http.Cookie{
+			Name:     wsSessionCookie,
+			Value:    newSessionID(),
+			Path:     "/",
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+		})
+	}
+
+	title := wui.Title
+	if title == "" {
+		title = "Snowman"
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := wsPage.Execute(w, map[string]string{"Title": title}); err != nil {
+		wui.Warnf("failed to render chat page: %v", err)
+	}
+}
+
+func (wui *WebSocketUI) serveSocket(w http.ResponseWriter, r *http.Request) {
+	wui.mu.RLock()
+	receive, inflight := wui.receive, wui.inflight
+	wui.mu.RUnlock()
+
+	if receive == nil {
+		http.Error(w, "not listening", http.StatusServiceUnavailable)
+		return
+	}
+
+	cookie, err := r.Cookie(wsSessionCookie)
+	if err != nil || cookie.Value == "" {
+		http.Error(w, "session cookie missing", http.StatusBadRequest)
+		return
+	}
+	session := sessionUserID(cookie.Value)
+
+	upgrader := websocket.Upgrader{CheckOrigin: wui.CheckOrigin}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		wui.Warnf("websocket upgrade failed: %v", err)
+		return
+	}
+
+	c := &wsConn{conn: conn}
+	if !wui.track(session, c, true) {
+		_ = conn.Close()
+		return
+	}
+	defer func() {
+		// once Listen returns, the socket is closed by closeAll instead.
+		if wui.track(session, c, false) {
+			_ = conn.Close()
+		}
+	}()
+
+	var lastID int
+	for {
+		var in wsIncoming
+		if err := conn.ReadJSON(&in); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				wui.Debugf("websocket read failed for session '%s': %v", session, err)
+			}
+			return
+		}
+
+		lastID++
+		msg := Msg{
+			ID:          session + "-" + strconv.Itoa(lastID),
+			At:          time.Now(),
+			From:        User{ID: session, Attribs: map[string]interface{}{"ws_session": session}},
+			Body:        in.Body,
+			Payload:     in.Payload,
+			Channel:     session,
+			ChannelType: ChannelDirect,
+			Raw:         in,
+		}
+		if !inflight.track(&msg) {
+			return
+		}
+		receive(msg)
+	}
+}
+
+// track adds or removes the socket of the session. Returns false if the UI
+// has stopped listening, in which case the sockets are left to closeAll.
+func (wui *WebSocketUI) track(session string, c *wsConn, add bool) bool {
+	wui.mu.Lock()
+	defer wui.mu.Unlock()
+
+	if wui.receive == nil {
+		return false
+	}
+
+	if add {
+		if wui.sessions[session] == nil {
+			wui.sessions[session] = map[*wsConn]struct{}{}
+		}
+		wui.sessions[session][c] = struct{}{}
+		return true
+	}
+
+	delete(wui.sessions[session], c)
+	if len(wui.sessions[session]) == 0 {
+		delete(wui.sessions, session)
+	}
+	return true
+}
+
+// stopReading stops accepting new sockets and messages. The open sockets are
+// closed once the bot is done with the messages already received.
+func (wui *WebSocketUI) stopReading() {
+	wui.mu.Lock()
+	wui.receive = nil
+	for _, conns := range wui.sessions {
+		for c := range conns {
+			_ = c.conn.SetReadDeadline(time.Now())
+		}
+	}
+	inflight := wui.inflight
+	wui.mu.Unlock()
+
+	inflight.closeThen(wui.closeAll)
+}
+
+func (wui *WebSocketUI) closeAll() {
+	wui.mu.Lock()
+	defer wui.mu.Unlock()
+
+	for _, conns := range wui.sessions {
+		for c := range conns {
+			_ = c.conn.Close()
+		}
+	}
+	wui.sessions = nil
+}
+
+func (c *wsConn) write(msg Msg) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_ = c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return c.conn.WriteJSON(msg)
+}
+
+func newSessionID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// sessionUserID derives the ID of the user from the session cookie. The cookie
+// itself never leaves the server since the ID is sent back to the page with
+// every message, logged and stored as the dialogue ID.
+func sessionUserID(cookie string) string {
+	sum := sha256.Sum256([]byte(cookie))
+	return hex.EncodeToString(sum[:16])
+}
+
+var wsPage = template.Must(template.New("chat").Parse(wsPageHTML))
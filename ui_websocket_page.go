@@ -0,0 +1,144 @@
+package snowman
+
+// wsPageHTML is the chat page served by WebSocketUI.
+const wsPageHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>{{.Title}}</title>
+  <style>
+    body { margin: 0; font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; background: #f4f6f8; }
+    #chat { display: flex; flex-direction: column; height: 100vh; max-width: 640px; margin: 0 auto; background: #fff; }
+    header { padding: 12px 16px; background: #2c3e50; color: #fff; font-weight: bold; }
+    #log { flex: 1; overflow-y: auto; padding: 16px; }
+    .msg { margin: 6px 0; padding: 8px 12px; border-radius: 12px; max-width: 80%; white-space: pre-wrap; word-wrap: break-word; }
+    .bot { background: #ecf0f1; }
+    .user { background: #3498db; color: #fff; margin-left: auto; }
+    .msg img { display: block; max-width: 100%; margin-top: 6px; border-radius: 6px; }
+    .choices button, .choices a { margin: 4px 4px 0 0; padding: 4px 10px; border: 1px solid #3498db; border-radius: 12px; background: #fff; color: #3498db; cursor: pointer; font-size: 0.9em; text-decoration: none; display: inline-block; }
+    .choices .primary { background: #3498db; color: #fff; }
+    .choices .danger { border-color: #e74c3c; color: #e74c3c; }
+    form { display: flex; border-top: 1px solid #ddd; }
+    input { flex: 1; padding: 12px; border: none; font-size: 1em; outline: none; }
+    form button { padding: 0 20px; border: none; background: #3498db; color: #fff; font-size: 1em; cursor: pointer; }
+    .status { text-align: center; color: #999; font-size: 0.8em; }
+  </style>
+</head>
+<body>
+<div id="chat">
+  <header>{{.Title}}</header>
+  <div id="log"></div>
+  <form id="form" autocomplete="off">
+    <input id="input" placeholder="Say something..." autofocus>
+    <button type="submit">Send</button>
+  </form>
+</div>
+<script>
+(function () {
+  var log = document.getElementById("log");
+  var input = document.getElementById("input");
+  var socket;
+
+  function append(el) {
+    log.appendChild(el);
+    log.scrollTop = log.scrollHeight;
+  }
+
+  function status(text) {
+    var el = document.createElement("div");
+    el.className = "status";
+    el.textContent = text;
+    append(el);
+  }
+
+  function bubble(cls, text) {
+    var el = document.createElement("div");
+    el.className = "msg " + cls;
+    el.textContent = text;
+    return el;
+  }
+
+  function send(body, payload) {
+    if (!socket || socket.readyState !== WebSocket.OPEN || body === "") {
+      return;
+    }
+    socket.send(JSON.stringify({body: body, payload: payload || ""}));
+    append(bubble("user", body));
+  }
+
+  function choice(label, payload, url, style) {
+    var el;
+    if (url) {
+      el = document.createElement("a");
+      el.href = url;
+      el.target = "_blank";
+      el.rel = "noopener";
+    } else {
+      el = document.createElement("button");
+      el.onclick = function () { send(label, payload); };
+    }
+    el.textContent = label;
+    if (style) {
+      el.className = style;
+    }
+    return el;
+  }
+
+  function render(msg) {
+    var el = bubble("bot", [msg.body].concat(msg.blocks || []).filter(Boolean).join("\n"));
+
+    (msg.attachments || []).forEach(function (att) {
+      if (att.type === "image") {
+        var img = document.createElement("img");
+        img.src = att.url;
+        img.alt = att.title || att.name || "";
+        el.appendChild(img);
+      } else {
+        var link = document.createElement("a");
+        link.href = att.url;
+        link.target = "_blank";
+        link.rel = "noopener";
+        link.textContent = "\n" + (att.title || att.name || att.url);
+        el.appendChild(link);
+      }
+    });
+
+    var choices = document.createElement("div");
+    choices.className = "choices";
+    (msg.buttons || []).forEach(function (btn) {
+      choices.appendChild(choice(btn.label, btn.payload, btn.url, btn.style));
+    });
+    (msg.quick_replies || []).forEach(function (qr) {
+      choices.appendChild(choice(qr.label, qr.payload));
+    });
+    if (choices.childNodes.length > 0) {
+      el.appendChild(choices);
+    }
+    append(el);
+  }
+
+  function connect() {
+    var url = new URL("ws", window.location.href);
+    url.protocol = url.protocol === "https:" ? "wss:" : "ws:";
+    socket = new WebSocket(url.toString());
+    socket.onopen = function () { status("connected"); };
+    socket.onmessage = function (ev) { render(JSON.parse(ev.data)); };
+    socket.onclose = function () {
+      status("disconnected, reconnecting...");
+      setTimeout(connect, 2000);
+    };
+  }
+
+  document.getElementById("form").onsubmit = function (ev) {
+    ev.preventDefault();
+    send(input.value.trim());
+    input.value = "";
+  };
+
+  connect();
+})();
+</script>
+</body>
+</html>
+`
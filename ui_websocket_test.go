@@ -0,0 +1,152 @@
+package snowman_test
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/spy16/snowman"
+)
+
+func TestWebSocketUI(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Echo", func(t *testing.T) {
+		ui := &snowman.WebSocketUI{Title: "Test Bot"}
+		srv := httptest.NewServer(ui)
+		defer srv.Close()
+		runBot(t, ui, snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+			return di.Say(msg.Context(), "echo: "+msg.Body+msg.Payload)
+		}))
+
+		conn, _ := dialWebSocket(t, srv.URL)
+		if err := conn.WriteJSON(map[string]string{"body": "yes", "payload": "!"}); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+
+		if got := readWebSocket(t, conn); got.Body != "echo: yes!" {
+			t.Errorf("reply want body 'echo: yes!', got '%s'", got.Body)
+		}
+	})
+
+	t.Run("SessionCookie", func(t *testing.T) {
+		ui := &snowman.WebSocketUI{}
+		srv := httptest.NewServer(ui)
+		defer srv.Close()
+		runBot(t, ui, snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+			return di.Say(msg.Context(), "hello "+msg.From.ID+" "+msg.Channel+" "+msg.ID)
+		}))
+
+		conn, cookie := dialWebSocket(t, srv.URL)
+		if err := conn.WriteJSON(map[string]string{"body": "hi"}); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+
+		// the HttpOnly session cookie must not be exposed to the page (or
+		// the logs and stores) through the IDs of the user.
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		if !strings.Contains(string(data), "hello ") {
+			t.Fatalf("want reply, got %s", data)
+		}
+		if strings.Contains(string(data), cookie) {
+			t.Errorf("reply must not contain the session cookie, got %s", data)
+		}
+	})
+
+	t.Run("Drain", func(t *testing.T) {
+		ui := &snowman.WebSocketUI{}
+		srv := httptest.NewServer(ui)
+		defer srv.Close()
+
+		started := make(chan struct{}, 1)
+		stop := startBot(t, &snowman.Bot{
+			UI: ui,
+			Handler: snowman.Fn(func(msg *snowman.Msg, di snowman.Dialogue) error {
+				started <- struct{}{}
+				time.Sleep(50 * time.Millisecond)
+				return di.Say(msg.Context(), "late reply")
+			}),
+		})
+
+		conn, _ := dialWebSocket(t, srv.URL)
+		if err := conn.WriteJSON(map[string]string{"body": "hi"}); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+		waitN(t, started, 1)
+
+		// the reply is produced while the bot drains, after Listen returned.
+		if err := stop(); err != nil {
+			t.Fatalf("Run() unexpected error: %v", err)
+		}
+
+		if got := readWebSocket(t, conn); got.Body != "late reply" {
+			t.Errorf("reply want body 'late reply', got '%s'", got.Body)
+		}
+	})
+}
+
+// dialWebSocket gets a session cookie from the chat page and connects to the
+// websocket endpoint, retrying while the UI is not listening yet. Returns the
+// connection and the value of the session cookie.
+func dialWebSocket(t *testing.T, srvURL string) (*websocket.Conn, string) {
+	t.Helper()
+
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+
+	resp, err := client.Get(srvURL + "/")
+	if err != nil {
+		t.Fatalf("GET / failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET / want status 200, got %d", resp.StatusCode)
+	}
+
+	wsURL := "ws" + strings.TrimPrefix(srvURL, "http") + "/ws"
+	header := http.Header{}
+	var session string
+	for _, c := range jar.Cookies(resp.Request.URL) {
+		header.Add("Cookie", c.String())
+		if c.Name == "snowman_session" {
+			session = c.Value
+		}
+	}
+	if session == "" {
+		t.Fatalf("GET / did not set the session cookie")
+	}
+	header.Set("Origin", srvURL)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		conn, resp, err := websocket.DefaultDialer.Dial(wsURL, header)
+		if err == nil {
+			t.Cleanup(func() { conn.Close() })
+			return conn, session
+		}
+		if resp == nil || resp.StatusCode != http.StatusServiceUnavailable || time.Now().After(deadline) {
+			t.Fatalf("websocket dial failed: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func readWebSocket(t *testing.T, conn *websocket.Conn) snowman.Msg {
+	t.Helper()
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var got snowman.Msg
+	if err := conn.ReadJSON(&got); err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	return got
+}
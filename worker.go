@@ -0,0 +1,115 @@
+package snowman
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWorkers         = 8
+	defaultQueueSize       = 32
+	defaultShutdownTimeout = 10 * time.Second
+)
+
+// workerPool processes messages concurrently using at most a fixed number of
+// workers. Every dialogue has its own queue that is drained in order by a
+// single goroutine, so messages of a dialogue are processed one at a time
+// while a slow dialogue only holds up its own messages.
+type workerPool struct {
+	ctx    context.Context
+	handle func(ctx context.Context, msg Msg)
+
+	// workers limits the number of messages processed at once and room the
+	// number of messages submitted but not processed yet.
+	workers chan struct{}
+	room    chan struct{}
+	stopped chan struct{}
+	wg      sync.WaitGroup
+
+	// mu guards the queues and closed. a dialogue has an entry in queues
+	// as long as its queue is being drained.
+	mu     sync.Mutex
+	queues map[string][]Msg
+	closed bool
+}
+
+func startWorkers(ctx context.Context, count, queueSize int, handle func(ctx context.Context, msg Msg)) *workerPool {
+	return &workerPool{
+		ctx:     ctx,
+		handle:  handle,
+		workers: make(chan struct{}, count),
+		room:    make(chan struct{}, count*queueSize),
+		stopped: make(chan struct{}),
+		queues:  map[string][]Msg{},
+	}
+}
+
+// submit queues the message to the queue of the dialogue. It blocks if too
+// many messages are waiting until there is room or ctx is cancelled. Returns
+// false if the message was dropped because ctx was cancelled or the pool has
+// been stopped.
+func (wp *workerPool) submit(ctx context.Context, key string, msg Msg) bool {
+	select {
+	case wp.room <- struct{}{}:
+	case <-wp.stopped:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	if wp.closed {
+		<-wp.room
+		return false
+	}
+
+	q, draining := wp.queues[key]
+	wp.queues[key] = append(q, msg)
+	if !draining {
+		wp.wg.Add(1)
+		go wp.drain(key)
+	}
+	return true
+}
+
+// drain processes the messages of the dialogue in order until its queue is
+// empty.
+func (wp *workerPool) drain(key string) {
+	defer wp.wg.Done()
+
+	for {
+		wp.mu.Lock()
+		q := wp.queues[key]
+		if len(q) == 0 {
+			delete(wp.queues, key)
+			wp.mu.Unlock()
+			return
+		}
+		msg := q[0]
+		q[0] = Msg{}
+		wp.queues[key] = q[1:]
+		wp.mu.Unlock()
+
+		wp.workers <- struct{}{}
+		if wp.ctx.Err() != nil {
+			// shutdown deadline exceeded. drop remaining messages.
+			msg.finish()
+		} else {
+			wp.handle(wp.ctx, msg)
+		}
+		<-wp.workers
+		<-wp.room
+	}
+}
+
+// stop stops accepting new messages and waits for the messages already queued
+// to be processed. Messages submitted after stop are dropped.
+func (wp *workerPool) stop() {
+	wp.mu.Lock()
+	wp.closed = true
+	close(wp.stopped)
+	wp.mu.Unlock()
+	wp.wg.Wait()
+}